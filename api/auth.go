@@ -1,9 +1,14 @@
 package api
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/url"
 )
 
 type AuthClient struct {
@@ -16,6 +21,11 @@ func NewAuthClient(accessToken string) *AuthClient {
 	}
 }
 
+// WithContext returns a copy of the client whose requests are bound to ctx.
+func (c *AuthClient) WithContext(ctx context.Context) *AuthClient {
+	return &AuthClient{HTTPClient: c.HTTPClient.WithContext(ctx)}
+}
+
 // NewAuthClientWithConfig creates a new AuthClient with the access token loaded from the credential file.
 // Returns a new AuthClient with empty access token if loading credential fails.
 func NewAuthClientWithConfig() *AuthClient {
@@ -61,32 +71,94 @@ type AccessPayload struct {
 	ClientSecret string `json:"client_secret"`
 	Code         string `json:"code"`
 	RedirectUri  string `json:"redirect_uri"`
+	CodeVerifier string `json:"code_verifier,omitempty"`
+}
+
+// DefaultCallbackPort is the port of the redirect URI registered for the app
+const DefaultCallbackPort = 9090
+
+// RedirectURI returns the OAuth redirect URI of the local callback server
+func RedirectURI(port int) string {
+	return fmt.Sprintf("http://localhost:%d/auth", port)
+}
+
+// Authorization is one authorization request. State protects the callback against
+// forged redirects, and Verifier is the PKCE secret that only this client knows.
+type Authorization struct {
+	RedirectURI string
+	State       string
+	Verifier    string
 }
 
-func (c *AuthClient) GetAccessToken(code string) {
+// NewAuthorization creates an authorization request with a random state and PKCE verifier
+func NewAuthorization(redirectURI string) (*Authorization, error) {
+	state, err := randomString(24)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	return &Authorization{RedirectURI: redirectURI, State: state, Verifier: verifier}, nil
+}
+
+// URL returns the page on bgm.tv where the user grants access
+func (a *Authorization) URL() string {
+	challenge := sha256.Sum256([]byte(a.Verifier))
+	query := url.Values{}
+	query.Set("client_id", ClientId)
+	query.Set("response_type", "code")
+	query.Set("redirect_uri", a.RedirectURI)
+	query.Set("state", a.State)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	return "https://bgm.tv/oauth/authorize?" + query.Encode()
+}
+
+// randomString returns n random bytes encoded as base64url without padding
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GetAccessToken exchanges the authorization code of auth for a token and saves the credential.
+func (c *AuthClient) GetAccessToken(code string, auth *Authorization) error {
 	payload := AccessPayload{
 		GrantType:    GrantType,
 		ClientId:     ClientId,
 		ClientSecret: AppSecret,
 		Code:         code,
-		RedirectUri:  "http://localhost:9090/auth",
+		RedirectUri:  auth.RedirectURI,
+		CodeVerifier: auth.Verifier,
 	}
 	data, err := json.Marshal(payload)
-	AbortOnError(err)
+	if err != nil {
+		return err
+	}
 	b, err := c.Post(API_AUTH, data)
-	AbortOnError(err)
+	if err != nil {
+		return err
+	}
 
 	credential := Credential{}
 	err = json.Unmarshal(b, &credential)
 	if err != nil {
 		slog.Error(fmt.Sprintf("unmarshalling credential: %v", err))
-		return
+		return err
+	}
+	if credential.AccessToken == "" {
+		return fmt.Errorf("no access token in response: %s", b)
 	}
 
 	// Update the access token in the AuthClient
 	c.AccessToken = credential.AccessToken
 
 	SaveCredential(credential)
+	return nil
 }
 
 type RefreshPayload struct {
@@ -112,7 +184,7 @@ func (c *AuthClient) RefreshToken() (*Credential, error) {
 		ClientId:     ClientId,
 		ClientSecret: AppSecret,
 		RefreshToken: credential.RefreshToken,
-		RedirectUri:  "http://localhost:9090/auth",
+		RedirectUri:  RedirectURI(DefaultCallbackPort),
 	}
 
 	data, err := json.Marshal(payload)
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JST is the timezone of air dates and broadcast times on Bangumi
+var JST = loadJST()
+
+func loadJST() *time.Location {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		// Japan has no daylight saving time
+		return time.FixedZone("JST", 9*60*60)
+	}
+	return loc
+}
+
+// Broadcast is the weekly slot of a subject in JST, as written in the infobox.
+// Late-night slots keep the broadcaster's notation: 25:30 on Friday is 01:30 on Saturday.
+type Broadcast struct {
+	Weekday time.Weekday `json:"weekday"` // Nominal weekday
+	Minutes int          `json:"minutes"` // Minutes after the start of the nominal day, -1 if the time is unknown
+}
+
+// Infobox keys with the broadcast weekday, time or first air date
+var broadcastKeys = []string{"放送星期", "放送时间", "放送開始", "放送开始"}
+
+var (
+	clockPattern = regexp.MustCompile(`(\d{1,2})\s*[:：時时]\s*(\d{2})`)
+	datePattern  = regexp.MustCompile(`(\d{4})\s*[年\-/.]\s*(\d{1,2})\s*[月\-/.]\s*(\d{1,2})`)
+)
+
+// weekdayNames are Chinese, Japanese and English names of weekdays.
+// Longer names come first so "星期日" is not matched as "日".
+var weekdayNames = []struct {
+	name    string
+	weekday time.Weekday
+}{
+	{"星期一", time.Monday}, {"星期二", time.Tuesday}, {"星期三", time.Wednesday}, {"星期四", time.Thursday},
+	{"星期五", time.Friday}, {"星期六", time.Saturday}, {"星期日", time.Sunday}, {"星期天", time.Sunday},
+	{"周一", time.Monday}, {"周二", time.Tuesday}, {"周三", time.Wednesday}, {"周四", time.Thursday},
+	{"周五", time.Friday}, {"周六", time.Saturday}, {"周日", time.Sunday}, {"周天", time.Sunday},
+	{"月曜", time.Monday}, {"火曜", time.Tuesday}, {"水曜", time.Wednesday}, {"木曜", time.Thursday},
+	{"金曜", time.Friday}, {"土曜", time.Saturday}, {"日曜", time.Sunday},
+	{"monday", time.Monday}, {"tuesday", time.Tuesday}, {"wednesday", time.Wednesday}, {"thursday", time.Thursday},
+	{"friday", time.Friday}, {"saturday", time.Saturday}, {"sunday", time.Sunday},
+}
+
+// ParseWeekday finds the name of a weekday in text
+func ParseWeekday(text string) (time.Weekday, bool) {
+	text = strings.ToLower(text)
+	for _, w := range weekdayNames {
+		if strings.Contains(text, w.name) {
+			return w.weekday, true
+		}
+	}
+	return time.Sunday, false
+}
+
+// ParseClock finds a time like "23:00" or "25:30" in text and returns the minutes after midnight.
+// Hours up to 29 are late-night slots of the previous day.
+func ParseClock(text string) (int, bool) {
+	for _, m := range clockPattern.FindAllStringSubmatch(text, -1) {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		if hour < 30 && minute < 60 {
+			return hour*60 + minute, true
+		}
+	}
+	return -1, false
+}
+
+// parseInfoDate finds a date like "2024年4月5日" or "2024-04-05" in text
+func parseInfoDate(text string) (time.Time, bool) {
+	m := datePattern.FindStringSubmatch(text)
+	if m == nil {
+		return time.Time{}, false
+	}
+	year, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	day, _ := strconv.Atoi(m[3])
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, JST), true
+}
+
+// ParseBroadcast reads the broadcast slot from the infobox. The weekday is taken from
+// 放送星期, or the first air date if missing. It reports false if the weekday is unknown.
+func (s *Subject) ParseBroadcast() (Broadcast, bool) {
+	b := Broadcast{Minutes: -1}
+	weekdayFound := false
+	for _, key := range broadcastKeys {
+		for _, value := range s.InfoBoxValues(key) {
+			if !weekdayFound {
+				if weekday, ok := ParseWeekday(value); ok {
+					b.Weekday, weekdayFound = weekday, true
+				} else if date, ok := parseInfoDate(value); ok {
+					b.Weekday, weekdayFound = date.Weekday(), true
+				}
+			}
+			if b.Minutes < 0 {
+				b.Minutes, _ = ParseClock(value)
+			}
+		}
+	}
+	return b, weekdayFound
+}
+
+// HasTime reports whether the time of day is known
+func (b Broadcast) HasTime() bool {
+	return b.Minutes >= 0
+}
+
+// String is the slot in the broadcaster's notation, e.g. "Fri 25:30 JST"
+func (b Broadcast) String() string {
+	if !b.HasTime() {
+		return b.Weekday.String()[:3]
+	}
+	return fmt.Sprintf("%s %02d:%02d JST", b.Weekday.String()[:3], b.Minutes/60, b.Minutes%60)
+}
+
+// Next returns the next start of the slot at or after now. The start of the day is used
+// if the time is unknown. Convert it with In to get the weekday and time of another timezone.
+func (b Broadcast) Next(now time.Time) time.Time {
+	minutes := max(b.Minutes, 0)
+	jst := now.In(JST)
+	midnight := time.Date(jst.Year(), jst.Month(), jst.Day(), 0, 0, 0, 0, JST)
+	// A late-night slot of yesterday may still be ahead
+	for d := -2; ; d++ {
+		day := midnight.AddDate(0, 0, d)
+		if day.Weekday() != b.Weekday {
+			continue
+		}
+		if start := day.Add(time.Duration(minutes) * time.Minute); !start.Before(now) {
+			return start
+		}
+	}
+}
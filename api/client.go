@@ -2,10 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 
+	"github.com/iucario/bangumi-go/internal/credstore"
 	"github.com/iucario/bangumi-go/util"
 )
 
@@ -27,18 +29,48 @@ type Credential struct {
 	UserId       int    `json:"user_id"`
 }
 
-// Save credential to file
+// CredentialStoreKind is credential_store of the config, set by the command before it
+// reads credentials. BGM_CREDENTIAL_STORE overrides it.
+var CredentialStoreKind string
+
+// OpenCredentialStore opens the credential store of a profile directory. The kind is taken from
+// BGM_CREDENTIAL_STORE or CredentialStoreKind. Tests can replace it with a credstore.Memory.
+var OpenCredentialStore = func(profile, dir string) (credstore.Store, error) {
+	kind := os.Getenv("BGM_CREDENTIAL_STORE")
+	if kind == "" {
+		kind = CredentialStoreKind
+	}
+	return credstore.Open(kind, credstore.Options{
+		Dir:            dir,
+		Profile:        profile,
+		Password:       credentialPassword.Password,
+		ForgetPassword: credentialPassword.Forget,
+		Warn:           os.Stderr,
+	})
+}
+
+// credentialPassword asks for the password of an encrypted credential once per run
+var credentialPassword = &credstore.PasswordCache{Prompt: credstore.PromptPassword}
+
+// Save credential to the store of the active profile
 func SaveCredential(credential Credential) {
-	configDir := util.ConfigDir()
-	err := os.MkdirAll(configDir, 0o755)
+	store, err := OpenCredentialStore(util.Profile(), util.ConfigDir())
 	AbortOnError(err)
 
 	jsonBytes, err := json.Marshal(credential)
 	AbortOnError(err)
 
-	credentialPath := fmt.Sprintf("%s/credential.json", configDir)
-	err = os.WriteFile(credentialPath, jsonBytes, 0o644)
-	AbortOnError(err)
+	AbortOnError(store.Save(jsonBytes))
+}
+
+// DeleteCredential removes the credential of the active profile, and a plain text one left from before
+func DeleteCredential() error {
+	store, err := OpenCredentialStore(util.Profile(), util.ConfigDir())
+	if err != nil {
+		return err
+	}
+	plain := &credstore.PlainFile{Path: credstore.PlainPath(util.ConfigDir())}
+	return errors.Join(store.Delete(), plain.Delete())
 }
 
 // Handle all errors and refresh token. Throw error if a login is required.
@@ -47,6 +79,10 @@ func GetCredential() (*Credential, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Personal access tokens from bgm auth token cannot be refreshed
+	if credential.RefreshToken == "" {
+		return &credential, nil
+	}
 	authClient := NewAuthClient(credential.AccessToken)
 	statusFlag := authClient.GetStatus()
 	if statusFlag {
@@ -68,11 +104,21 @@ func AbortOnError(err error) {
 	}
 }
 
-// Load credential JSON from file
+// Load credential JSON of the active profile
 func loadCredential() (Credential, error) {
-	configDir := util.ConfigDir()
-	credentialPath := fmt.Sprintf("%s/credential.json", configDir)
-	jsonBytes, err := os.ReadFile(credentialPath)
+	return LoadProfileCredential(util.Profile())
+}
+
+// LoadProfileCredential reads the credential of a profile without checking the token.
+// A plain text credential of an earlier version is moved to the configured store.
+func LoadProfileCredential(profile string) (Credential, error) {
+	dir := util.ProfileDir(profile)
+	store, err := OpenCredentialStore(profile, dir)
+	if err != nil {
+		return Credential{}, err
+	}
+	plain := &credstore.PlainFile{Path: credstore.PlainPath(dir), Warn: os.Stderr}
+	jsonBytes, err := credstore.Load(store, plain)
 	if err != nil {
 		return Credential{}, err
 	}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
@@ -35,6 +36,7 @@ type Client interface {
 // Access token is optional.
 type HTTPClient struct {
 	http        *http.Client
+	ctx         context.Context
 	AccessToken string
 }
 
@@ -47,6 +49,14 @@ func NewHTTPClient(accessToken string) *HTTPClient {
 	}
 }
 
+// WithContext returns a copy of the client whose requests are bound to ctx.
+// Cancelling ctx aborts requests in flight.
+func (c *HTTPClient) WithContext(ctx context.Context) *HTTPClient {
+	client := *c
+	client.ctx = ctx
+	return &client
+}
+
 func (c *HTTPClient) Get(url string) ([]byte, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -85,6 +95,9 @@ func (c *HTTPClient) request(req *http.Request) ([]byte, error) {
 	if c.AccessToken != "" {
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.AccessToken))
 	}
+	if c.ctx != nil {
+		req = req.WithContext(c.ctx)
+	}
 
 	res, err := c.http.Do(req)
 	if err != nil {
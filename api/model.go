@@ -1,6 +1,9 @@
 package api
 
 import (
+	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -139,6 +142,46 @@ type Subject struct {
 	InfoBox []map[string]any `json:"infobox"` // A list of ordered maps for additional information
 }
 
+// InfoBoxValues returns values of an infobox key. A value can be a string or a list of {"v": string}.
+func (s *Subject) InfoBoxValues(key string) []string {
+	var values []string
+	for _, item := range s.InfoBox {
+		if item["key"] != key {
+			continue
+		}
+		switch v := item["value"].(type) {
+		case string:
+			values = append(values, strings.TrimSpace(v))
+		case []any:
+			for _, entry := range v {
+				if m, ok := entry.(map[string]any); ok {
+					if name, ok := m["v"].(string); ok {
+						values = append(values, strings.TrimSpace(name))
+					}
+				}
+			}
+		}
+	}
+	return values
+}
+
+// InfoBoxNames splits infobox values of people or companies like "A、B" or "A / B" into names.
+func (s *Subject) InfoBoxNames(key string) []string {
+	var names []string
+	for _, value := range s.InfoBoxValues(key) {
+		for _, name := range strings.FieldsFunc(value, isNameSeparator) {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func isNameSeparator(r rune) bool {
+	return r == '、' || r == '/' || r == '，' || r == ','
+}
+
 type SlimSubject struct {
 	ID              uint32            `json:"id"`
 	Type            uint32            `json:"type"`
@@ -162,6 +205,23 @@ func (s *SlimSubject) GetName() string {
 	return s.Name
 }
 
+// RelatedSubject is a subject linked to another, e.g. a sequel or the original work
+type RelatedSubject struct {
+	ID       uint32            `json:"id"`
+	Type     uint32            `json:"type"`
+	Name     string            `json:"name"`
+	NameCn   string            `json:"name_cn"`
+	Images   map[string]string `json:"images"`
+	Relation string            `json:"relation"` // E.g. 续集, 前传, 番外篇
+}
+
+func (s *RelatedSubject) GetName() string {
+	if s.NameCn != "" {
+		return s.NameCn
+	}
+	return s.Name
+}
+
 func (s *SlimSubject) GetAllTags() string {
 	return tagNames(s.Tags)
 }
@@ -222,18 +282,24 @@ func (e *Episodes) Status() {
 	panic("Not impelemented")
 }
 
-// Latest on aired episode Sort
+// Latest returns the number in the subject of the latest aired main episode, -1 if none has aired.
+// Episodes without an air date are not scheduled yet and skipped.
 func (e *Episodes) Latest() int {
 	today := time.Now()
 	// TODO: When the episode list is too long, the data may not contain the latest episode.
 
-	// FIXME: binary search
 	for i := len(e.Data) - 1; i >= 0; i -= 1 {
+		if e.Data[i].Type != 0 {
+			continue
+		}
 		parsed, err := parseDate(e.Data[i].Airdate)
 		if err != nil {
-			return -1
+			continue
 		}
 		if parsed.Before(today) {
+			if e.Data[i].Ep > 0 {
+				return e.Data[i].Ep
+			}
 			return e.Data[i].Sort
 		}
 	}
@@ -264,17 +330,20 @@ func (e *Episode) GetName() string {
 	return "N/A"
 }
 
+// OnAirToday reports whether the air date, which is in JST, is today in Japan
 func (e *Episode) OnAirToday() bool {
-	today := time.Now()
-	today = today.Add(time.Hour * 9) // TO JST
-	todayStr := today.Format("2006-01-02")
-	return e.Airdate == todayStr
+	return e.Airdate == time.Now().In(JST).Format("2006-01-02")
 }
 
 func (e *Episode) GetAirTime() (time.Time, error) {
 	return parseDate(e.Airdate)
 }
 
+// GetDuration parses Duration, which is usually "HH:MM:SS" but can be "MM:SS" or "24m".
+func (e *Episode) GetDuration() (time.Duration, error) {
+	return parseDuration(e.Duration)
+}
+
 type Tag struct {
 	Name  string
 	Count int
@@ -287,17 +356,82 @@ type Rating struct {
 	Score float64 `json:"score"`
 }
 
+// Count is the number of votes of each score. The API returns it as a map of "1" to "10".
 type Count struct {
-	Field1  uint32
-	Field2  uint32
-	Field3  uint32
-	Field4  uint32
-	Field5  uint32
-	Field6  uint32
-	Field7  uint32
-	Field8  uint32
-	Field9  uint32
-	Field10 uint32
+	Field1  uint32 `json:"1"`
+	Field2  uint32 `json:"2"`
+	Field3  uint32 `json:"3"`
+	Field4  uint32 `json:"4"`
+	Field5  uint32 `json:"5"`
+	Field6  uint32 `json:"6"`
+	Field7  uint32 `json:"7"`
+	Field8  uint32 `json:"8"`
+	Field9  uint32 `json:"9"`
+	Field10 uint32 `json:"10"`
+}
+
+// Votes returns the number of votes indexed by score. Index 0 is always 0.
+func (c Count) Votes() [11]uint32 {
+	return [11]uint32{
+		0, c.Field1, c.Field2, c.Field3, c.Field4, c.Field5,
+		c.Field6, c.Field7, c.Field8, c.Field9, c.Field10,
+	}
+}
+
+// Total returns the number of votes in the histogram
+func (c Count) Total() uint32 {
+	var total uint32
+	for _, n := range c.Votes() {
+		total += n
+	}
+	return total
+}
+
+// Mean returns the mean score of the histogram. 0 if there are no votes.
+func (r Rating) Mean() float64 {
+	total := r.Count.Total()
+	if total == 0 {
+		return 0
+	}
+	var sum float64
+	for score, n := range r.Count.Votes() {
+		sum += float64(score) * float64(n)
+	}
+	return sum / float64(total)
+}
+
+// StdDev returns the standard deviation of the votes. 0 if there are no votes.
+func (r Rating) StdDev() float64 {
+	total := r.Count.Total()
+	if total == 0 {
+		return 0
+	}
+	mean := r.Mean()
+	var sum float64
+	for score, n := range r.Count.Votes() {
+		d := float64(score) - mean
+		sum += d * d * float64(n)
+	}
+	return math.Sqrt(sum / float64(total))
+}
+
+// Controversy describes how much voters disagree based on the standard deviation
+func (r Rating) Controversy() string {
+	std := r.StdDev()
+	switch {
+	case r.Count.Total() == 0:
+		return ""
+	case std < 1.0:
+		return "异口同声"
+	case std < 1.5:
+		return "基本一致"
+	case std < 2.0:
+		return "略有分歧"
+	case std < 2.5:
+		return "莫衷一是"
+	default:
+		return "众说纷纭"
+	}
 }
 
 type Calendar struct {
@@ -343,9 +477,35 @@ func tagNames(tags []Tag) string {
 	return strings.Join(names, " ")
 }
 
+// parseDuration parses an episode duration in the formats "HH:MM:SS", "MM:SS",
+// or Go duration strings such as "24m" and "1h30m".
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	if !strings.Contains(s, ":") {
+		return time.ParseDuration(s)
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid duration: %q", s)
+	}
+	var total time.Duration
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration: %q", s)
+		}
+		total = total*60 + time.Duration(n)
+	}
+	return total * time.Second, nil
+}
+
 // parseDate parses a date string in the format "2006-01-02"
 func parseDate(dateString string) (time.Time, error) {
-	parsedTime, err := time.Parse("2006-01-02", dateString)
+	// Air dates on Bangumi are in JST
+	parsedTime, err := time.ParseInLocation("2006-01-02", dateString, JST)
 	if err != nil {
 		return time.Now(), err
 	}
@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 )
 
 type User struct {
@@ -25,10 +26,36 @@ func NewUser(client *AuthClient) *User {
 	}
 }
 
+// NewUserByName creates a User for any username. Only public information is available.
+// Login is not required.
+func NewUserByName(client *AuthClient, username string) (*User, error) {
+	userInfo, err := GetUserInfoByName(client, username)
+	if err != nil {
+		return nil, err
+	}
+	return &User{
+		Client:   client,
+		UserInfo: userInfo,
+	}, nil
+}
+
 func (u *User) GetUserInfo() (*UserInfo, error) {
 	return getUserInfo(u.Client)
 }
 
+// GetUserInfoByName fetches the public profile of a user.
+func GetUserInfoByName(c Client, username string) (*UserInfo, error) {
+	b, err := c.Get(fmt.Sprintf("%s/users/%s", API, url.PathEscape(username)))
+	if err != nil {
+		return nil, err
+	}
+	userInfo := UserInfo{}
+	if err := json.Unmarshal(b, &userInfo); err != nil {
+		return nil, err
+	}
+	return &userInfo, nil
+}
+
 type UserInfo struct {
 	Avatar struct {
 		Large  string `json:"large"`
@@ -46,6 +73,32 @@ func (u UserInfo) String() string {
 	return fmt.Sprintf("User: %s, Nickname: %s, ID: %d", u.Username, u.Nickname, u.Id)
 }
 
+// UserGroupName maps user_group to its name on bgm.tv
+var UserGroupName = map[int]string{
+	1:  "管理员",
+	2:  "Bangumi 管理猿",
+	3:  "天窗管理猿",
+	4:  "禁言用户",
+	5:  "禁止访问用户",
+	8:  "人物管理猿",
+	9:  "维基条目管理猿",
+	10: "用户",
+	11: "维基人",
+}
+
+// GroupName returns the name of the user group
+func (u UserInfo) GroupName() string {
+	if name, ok := UserGroupName[u.UserGroup]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", u.UserGroup)
+}
+
+// URL returns the profile page on bgm.tv
+func (u UserInfo) URL() string {
+	return fmt.Sprintf("https://bgm.tv/user/%s", u.Username)
+}
+
 type DefaultResponse struct {
 	Request string `json:"request"`
 	Code    string `json:"code"`
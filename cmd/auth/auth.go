@@ -7,22 +7,22 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var ConfigDir string
-
 // authCmd represents the auth command
 var authCmd = &cobra.Command{
 	Use:   "auth",
 	Short: "Auth commands",
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println(`Available commands: 
-bgm auth login
+bgm auth login [--headless]
+bgm auth token
 bgm auth logout
 bgm auth status
-bgm auth refresh`)
+bgm auth refresh
+bgm auth list
+bgm auth switch <profile>`)
 	},
 }
 
 func init() {
 	cmd.RootCmd.AddCommand(authCmd)
-	ConfigDir = cmd.ConfigDir
 }
@@ -1,127 +1,245 @@
 package auth
 
 import (
+	"bufio"
 	"context"
+	"crypto/subtle"
 	"errors"
 	"fmt"
-	"log"
+	"html/template"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
-	"os/exec"
-	"runtime"
-	"sync"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/util"
 	"github.com/spf13/cobra"
 )
 
-var port = 9090
+// loginTimeout is how long the callback server waits for the browser
+const loginTimeout = 5 * time.Minute
 
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Login to https://bgm.tv (bangumi.tv)",
+	Long: `Login to https://bgm.tv in a browser. The browser is redirected to a local server
+that receives the authorization code.
+
+Without a browser, e.g. over SSH, or when the port is taken, the login URL is printed.
+Open it on any device, then paste the whole URL the browser is redirected to.
+
+The redirect URI must match the callback registered for the OAuth app, http://localhost:9090/auth.
+--port changes it, so other ports are rejected by bgm.tv unless the app registers the matching callback.
+When 9090 is taken, keep the port: the pasted URL still works.`,
+	Example: `bgm auth login
+bgm auth login --headless`,
 	Run: func(cmd *cobra.Command, args []string) {
+		headless, _ := cmd.Flags().GetBool("headless")
+		port, _ := cmd.Flags().GetInt("port")
 		Client := api.NewAuthClientWithConfig()
-		if Client.GetStatus() {
+		if Client.AccessToken != "" && Client.GetStatus() {
 			fmt.Println("Token is still valid")
 			return
-		} else {
-			// Token does not exist, login from browser
-			BrowserLogin(Client)
-		}
-		_, err := Client.RefreshToken()
-		if err != nil {
-			BrowserLogin(Client)
 		}
+		api.AbortOnError(BrowserLogin(Client, LoginOptions{Port: port, Headless: headless}))
 	},
 }
 
-func BrowserLogin(c *api.AuthClient) {
+// LoginOptions configure the OAuth login
+type LoginOptions struct {
+	Port     int  // Port of the local callback server. 0 is api.DefaultCallbackPort.
+	Headless bool // Print the login URL and read the redirected URL from stdin
+}
+
+// BrowserLogin logs in with OAuth and saves the credential. It falls back to pasting
+// the redirected URL when no browser can be opened or the callback port is taken.
+func BrowserLogin(c *api.AuthClient, opts LoginOptions) error {
+	if opts.Port == 0 {
+		opts.Port = api.DefaultCallbackPort
+	}
+	auth, err := api.NewAuthorization(api.RedirectURI(opts.Port))
+	if err != nil {
+		return err
+	}
 	fmt.Println("Login to https://bgm.tv")
-	apiAuth := "https://bgm.tv/oauth/authorize"
-	responseType := "code"
-	host := fmt.Sprintf("http://localhost:%d/auth", port)
-	LOGIN_URL := fmt.Sprintf("%s?client_id=%s&response_type=%s&redirect_uri=%s", apiAuth, api.ClientId, responseType, host)
 
-	serverDone := &sync.WaitGroup{}
-	serverDone.Add(1)
-	start(serverDone, c)
+	if opts.Headless || !util.CanOpenBrowser() {
+		return pasteLogin(c, auth, os.Stdin)
+	}
+	listeners, err := listenLoopback(opts.Port)
+	if err != nil {
+		fmt.Printf("Cannot receive the login on port %d: %v\n", opts.Port, err)
+		return pasteLogin(c, auth, os.Stdin)
+	}
 
-	openBrowser(LOGIN_URL)
+	if err := util.OpenBrowser(auth.URL()); err != nil {
+		slog.Error("open browser", "error", err)
+	}
 	fmt.Println("If your browser is not opened automatically. Manually open this URL in browser and login:")
-	fmt.Println(LOGIN_URL)
+	fmt.Println(auth.URL())
+	fmt.Println("On a remote machine, run bgm auth login --headless instead.")
 
-	serverDone.Wait()
+	code, err := receiveCode(listeners, auth.State, loginTimeout)
+	if err != nil {
+		return err
+	}
+	return exchangeCode(c, code, auth)
 }
 
-func start(wg *sync.WaitGroup, c *api.AuthClient) {
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", port),
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+// listenLoopback listens on port of the loopback addresses, so only a browser on this machine
+// may deliver the code. localhost may resolve to ::1, which is added if IPv6 is available.
+func listenLoopback(port int) ([]net.Listener, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	listeners := []net.Listener{listener}
+	if listener6, err := net.Listen("tcp", net.JoinHostPort("::1", strconv.Itoa(port))); err == nil {
+		listeners = append(listeners, listener6)
+	} else {
+		slog.Debug("no IPv6 loopback for the login callback", "error", err)
 	}
+	return listeners, nil
+}
 
-	http.HandleFunc("/auth", func(w http.ResponseWriter, r *http.Request) {
-		code := r.URL.Query().Get("code")
-		if code != "" {
-			c.GetAccessToken(code)
-			fmt.Println("Login success.")
-			w.Header().Set("Connection", "close")
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			_, err := fmt.Fprint(w, successHTML())
-			if err != nil {
-				slog.Error("Error writing response", "error", err)
+// errStateMismatch means a redirect does not belong to the running login
+var errStateMismatch = errors.New("state does not match, this login was not started by bgm")
+
+// callbackResult is the code or the error of an authorization redirect
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// callbackHandler handles the redirect to /auth of the login with state and sends the first
+// result. Redirects with another state are rejected and do not end the login.
+func callbackHandler(state string, results chan<- callbackResult) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /auth", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		code, err := parseCallback(r.URL.Query(), state)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			writePage(w, "Authentication Failed", fmt.Sprintf("Login failed: %v. Return to the terminal to try again.", err))
+			if errors.Is(err, errStateMismatch) {
+				return
 			}
-			go func() {
-				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-				if err := srv.Shutdown(ctx); err != nil {
-					slog.Error("Server Shutdown", "error", err)
-				}
-			}()
 		} else {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			_, err := fmt.Fprintln(w, "Hi")
-			if err != nil {
-				slog.Error("Error writing response", "error", err)
-			}
+			w.Header().Set("Connection", "close")
+			writePage(w, "Authentication Successful", "Login successful. You can close this page now.")
+		}
+		select {
+		case results <- callbackResult{code: code, err: err}:
+		default:
 		}
 	})
+	return mux
+}
 
-	go func() {
-		defer wg.Done()
-		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-			slog.Error("ListenAndServe", "error", err)
+// receiveCode serves the callback on listeners until a redirect arrives or the timeout passes
+func receiveCode(listeners []net.Listener, state string, timeout time.Duration) (string, error) {
+	results := make(chan callbackResult, 1)
+	srv := &http.Server{
+		Handler:      callbackHandler(state, results),
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+	for _, listener := range listeners {
+		go func() {
+			if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("Serve", "error", err)
+			}
+		}()
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			slog.Error("Server Shutdown", "error", err)
 		}
 	}()
+
+	select {
+	case res := <-results:
+		return res.code, res.err
+	case <-time.After(timeout):
+		return "", fmt.Errorf("no login within %s, try bgm auth login --headless", timeout)
+	}
+}
+
+// pasteLogin prints the login URL and reads the redirected URL from r
+func pasteLogin(c *api.AuthClient, auth *api.Authorization, r io.Reader) error {
+	fmt.Println("Open this URL in a browser on any device and login:")
+	fmt.Println(auth.URL())
+	fmt.Printf("The browser is then sent to %s, which may fail to load.\n", auth.RedirectURI)
+	fmt.Print("Paste the whole URL from the address bar: ")
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("read URL: %w", err)
+	}
+	code, err := ParseCode(line, auth.State)
+	if err != nil {
+		return err
+	}
+	return exchangeCode(c, code, auth)
 }
 
-func openBrowser(url string) {
-	var err error
+func exchangeCode(c *api.AuthClient, code string, auth *api.Authorization) error {
+	if err := c.GetAccessToken(code, auth); err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	fmt.Println("Login success.")
+	return nil
+}
 
-	switch runtime.GOOS {
-	case "linux":
-		err = exec.Command("xdg-open", url).Start()
-	case "windows":
-		err = exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
-	case "darwin":
-		err = exec.Command("open", url).Start()
-	default:
-		err = fmt.Errorf("unsupported platform")
+// ParseCode reads the authorization code from a redirected URL. The state in the URL must match,
+// so a bare code is not accepted.
+func ParseCode(input, state string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", errors.New("no URL given")
 	}
+	u, err := url.Parse(input)
 	if err != nil {
-		log.Fatal(err)
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.RawQuery == "" {
+		return "", errors.New("no query in URL, paste the whole URL from the address bar")
+	}
+	return parseCallback(u.Query(), state)
+}
+
+// parseCallback returns the code in the query of a redirect. The state is checked
+// before the error, so a forged redirect cannot fail the login either.
+func parseCallback(query url.Values, state string) (string, error) {
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state)) != 1 {
+		return "", errStateMismatch
+	}
+	if reason := query.Get("error"); reason != "" {
+		if description := query.Get("error_description"); description != "" {
+			reason += ": " + description
+		}
+		return "", fmt.Errorf("authorization denied: %s", reason)
 	}
+	code := query.Get("code")
+	if code == "" {
+		return "", errors.New("no code in URL")
+	}
+	return code, nil
 }
 
-// successHTML returns the HTML for the success page
-func successHTML() string {
-	return `<!DOCTYPE html>
+// pageTemplate is the page shown in the browser after the redirect
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
 <html>
 <head>
-    <title>Authentication Successful</title>
+    <title>{{.Title}}</title>
     <style>
         body {
             font-family: Arial, sans-serif;
@@ -153,13 +271,22 @@ func successHTML() string {
 </head>
 <body>
     <div class="container">
-        <h1>Authentication Successful</h1>
-        <p>Login successful. You can close this page now.</p>
+        <h1>{{.Title}}</h1>
+        <p>{{.Message}}</p>
     </div>
 </body>
-</html>`
+</html>`))
+
+// writePage writes the page with a title and a message. Both are escaped.
+func writePage(w io.Writer, title, message string) {
+	data := struct{ Title, Message string }{title, message}
+	if err := pageTemplate.Execute(w, data); err != nil {
+		slog.Error("Error writing response", "error", err)
+	}
 }
 
 func init() {
+	loginCmd.Flags().Bool("headless", false, "Print the login URL and paste the redirected URL instead of opening a browser")
+	loginCmd.Flags().Int("port", api.DefaultCallbackPort, "Port of the local login callback. Must match the callback registered for the OAuth app")
 	authCmd.AddCommand(loginCmd)
 }
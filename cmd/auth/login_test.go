@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testState = "state-1234"
+
+// callback sends a redirect with query to a handler of testState
+func callback(t *testing.T, query url.Values) (*httptest.ResponseRecorder, chan callbackResult) {
+	t.Helper()
+	results := make(chan callbackResult, 1)
+	rec := httptest.NewRecorder()
+	callbackHandler(testState, results).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth?"+query.Encode(), nil))
+	return rec, results
+}
+
+func TestCallbackHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    url.Values
+		status   int
+		page     string // Text in the page
+		sent     bool   // The login ends with a result
+		code     string
+		errorHas string
+	}{
+		{
+			name:   "valid state and code",
+			query:  url.Values{"code": {"abc"}, "state": {testState}},
+			status: http.StatusOK,
+			page:   "Authentication Successful",
+			sent:   true,
+			code:   "abc",
+		},
+		{
+			name:   "wrong state",
+			query:  url.Values{"code": {"abc"}, "state": {"forged"}},
+			status: http.StatusBadRequest,
+			page:   "state does not match",
+		},
+		{
+			name:   "missing state",
+			query:  url.Values{"code": {"abc"}},
+			status: http.StatusBadRequest,
+			page:   "state does not match",
+		},
+		{
+			name: "denied consent",
+			query: url.Values{
+				"error":             {"access_denied"},
+				"error_description": {"<script>alert(1)</script>"},
+				"state":             {testState},
+			},
+			status:   http.StatusBadRequest,
+			page:     "access_denied: &lt;script&gt;alert(1)&lt;/script&gt;",
+			sent:     true,
+			errorHas: "authorization denied: access_denied",
+		},
+		{
+			name:     "no code",
+			query:    url.Values{"state": {testState}},
+			status:   http.StatusBadRequest,
+			page:     "no code in URL",
+			sent:     true,
+			errorHas: "no code",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, results := callback(t, tt.query)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, tt.page) {
+				t.Errorf("page does not contain %q:\n%s", tt.page, body)
+			}
+			if strings.Contains(body, "<script>") {
+				t.Error("page contains unescaped input")
+			}
+			select {
+			case res := <-results:
+				if !tt.sent {
+					t.Fatalf("sent %+v, want nothing", res)
+				}
+				if res.code != tt.code {
+					t.Errorf("code = %q, want %q", res.code, tt.code)
+				}
+				if tt.errorHas == "" && res.err != nil {
+					t.Errorf("err = %v, want nil", res.err)
+				}
+				if tt.errorHas != "" && (res.err == nil || !strings.Contains(res.err.Error(), tt.errorHas)) {
+					t.Errorf("err = %v, want %q", res.err, tt.errorHas)
+				}
+			default:
+				if tt.sent {
+					t.Fatal("nothing sent, want a result")
+				}
+			}
+		})
+	}
+}
+
+func TestCallbackHandlerMethod(t *testing.T) {
+	results := make(chan callbackResult, 1)
+	rec := httptest.NewRecorder()
+	query := url.Values{"code": {"abc"}, "state": {testState}}
+	callbackHandler(testState, results).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth?"+query.Encode(), nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	if len(results) != 0 {
+		t.Error("POST ended the login")
+	}
+}
+
+func listen(t *testing.T, host string) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		t.Skipf("cannot listen on %s: %v", host, err)
+	}
+	return listener
+}
+
+func TestReceiveCode(t *testing.T) {
+	listener := listen(t, "127.0.0.1")
+	base := "http://" + listener.Addr().String() + "/auth?"
+	go func() {
+		// A forged redirect does not end the login, the real one afterwards does
+		for _, query := range []url.Values{
+			{"code": {"forged"}, "state": {"other"}},
+			{"code": {"abc"}, "state": {testState}},
+		} {
+			resp, err := http.Get(base + query.Encode())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}
+	}()
+	code, err := receiveCode([]net.Listener{listener}, testState, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "abc" {
+		t.Errorf("code = %q, want abc", code)
+	}
+}
+
+func TestReceiveCodeIPv6(t *testing.T) {
+	// localhost may resolve to ::1, where the redirect must arrive as well
+	listeners := []net.Listener{listen(t, "127.0.0.1"), listen(t, "::1")}
+	go func() {
+		query := url.Values{"code": {"abc"}, "state": {testState}}
+		resp, err := http.Get("http://" + listeners[1].Addr().String() + "/auth?" + query.Encode())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+	}()
+	code, err := receiveCode(listeners, testState, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "abc" {
+		t.Errorf("code = %q, want abc", code)
+	}
+}
+
+func TestListenLoopback(t *testing.T) {
+	port := listen(t, "127.0.0.1")
+	free := port.Addr().(*net.TCPAddr).Port
+	port.Close()
+	listeners, err := listenLoopback(free)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range listeners {
+		defer l.Close()
+		if ip := l.Addr().(*net.TCPAddr).IP; !ip.IsLoopback() {
+			t.Errorf("listening on %s, want a loopback address", ip)
+		}
+	}
+	// A taken port falls back to pasting the URL
+	if _, err := listenLoopback(free); err == nil {
+		t.Error("listened twice on the same port")
+	}
+}
+
+func TestReceiveCodeTimeout(t *testing.T) {
+	start := time.Now()
+	_, err := receiveCode([]net.Listener{listen(t, "127.0.0.1")}, testState, 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "no login within 50ms") {
+		t.Fatalf("err = %v, want timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("returned after %s", elapsed)
+	}
+}
+
+func TestParseCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		code    string
+		wantErr error
+	}{
+		{"redirect URL", "http://localhost:9090/auth?code=abc&state=" + testState + "\n", "abc", nil},
+		{"wrong state", "http://localhost:9090/auth?code=abc&state=forged", "", errStateMismatch},
+		{"URL without state", "http://localhost:9090/auth?code=abc", "", errStateMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := ParseCode(tt.input, testState)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if code != tt.code {
+				t.Errorf("code = %q, want %q", code, tt.code)
+			}
+		})
+	}
+
+	// A bare code has no state to check
+	for _, input := range []string{"", "abc123", "http://localhost:9090/auth", "http://localhost:9090/auth?error=access_denied&state=" + testState} {
+		if _, err := ParseCode(input, testState); err == nil {
+			t.Errorf("ParseCode(%q) succeeded", input)
+		}
+	}
+}
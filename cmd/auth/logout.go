@@ -4,27 +4,24 @@ import (
 	"fmt"
 	"log"
 	"log/slog"
-	"os"
 
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/util"
 	"github.com/spf13/cobra"
 )
 
 var logoutCmd = &cobra.Command{
 	Use:   "logout",
-	Short: "Auth commands",
+	Short: "Delete the credential of the profile",
 	Run: func(cmd *cobra.Command, args []string) {
-		deleteCredential()
-		log.Println("Logout success.")
+		if err := api.DeleteCredential(); err != nil {
+			slog.Error(fmt.Sprintf("Failed to delete credential: %s", err))
+			api.AbortOnError(err)
+		}
+		log.Printf("Logout success. Profile: %s\n", util.Profile())
 	},
 }
 
-func deleteCredential() {
-	err := os.RemoveAll(ConfigDir)
-	if err != nil {
-		slog.Error(fmt.Sprintf("Failed to delete config directory: %s", err))
-	}
-}
-
 func init() {
 	authCmd.AddCommand(logoutCmd)
 }
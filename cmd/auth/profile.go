@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/internal/credstore"
+	"github.com/iucario/bangumi-go/util"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles. The active one is marked with *",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		profiles, err := util.ListProfiles()
+		api.AbortOnError(err)
+		active := util.Profile()
+		// A profile from BGM_PROFILE or --profile may not have a directory yet
+		if !slices.Contains(profiles, active) {
+			profiles = append(profiles, active)
+		}
+		for _, name := range profiles {
+			mark := " "
+			if name == active {
+				mark = "*"
+			}
+			account := "not logged in"
+			if credential, err := api.LoadProfileCredential(name); err == nil {
+				account = fmt.Sprintf("user %d", credential.UserId)
+			} else if !errors.Is(err, credstore.ErrNotFound) {
+				account = err.Error()
+			}
+			fmt.Printf("%s %-16s %s\n", mark, name, account)
+		}
+	},
+}
+
+var switchCmd = &cobra.Command{
+	Use:   "switch <profile>",
+	Short: "Use a profile by default. It is created if missing",
+	Long: `Use a profile when --profile and BGM_PROFILE are not given.
+A new profile has its own credential, config and state. Login with bgm auth login after switching.`,
+	Example: `bgm auth switch club
+bgm auth switch default`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		api.AbortOnError(util.SwitchProfile(args[0]))
+		fmt.Printf("Switched to profile %s\n", args[0])
+		if _, err := api.LoadProfileCredential(args[0]); err != nil {
+			fmt.Println("Not logged in. Run bgm auth login")
+		}
+	},
+}
+
+func init() {
+	authCmd.AddCommand(listCmd)
+	authCmd.AddCommand(switchCmd)
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Login with a personal access token",
+	Long: `Login with a personal access token created at https://next.bgm.tv/demo/access-token.
+The token is asked without echo, or read from stdin with --stdin. It is checked before it is saved.
+Personal tokens cannot be refreshed. Create a new one when it expires.`,
+	Example: `bgm auth token
+pass show bgm | bgm auth token --stdin`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fromStdin, _ := cmd.Flags().GetBool("stdin")
+		token, err := readToken(fromStdin)
+		api.AbortOnError(err)
+
+		user := &api.User{Client: api.NewAuthClient(token)}
+		info, err := user.GetUserInfo()
+		if err != nil {
+			api.AbortOnError(fmt.Errorf("invalid token: %w", err))
+		}
+		api.SaveCredential(api.Credential{AccessToken: token, TokenType: "Bearer", UserId: info.Id})
+		fmt.Printf("Logged in as %s\n", info.Username)
+	},
+}
+
+// readToken reads one line from stdin, without echo on a terminal
+func readToken(fromStdin bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	var token string
+	if !fromStdin && term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Access token: ")
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		token = string(b)
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("read token: %w", err)
+		}
+		token = line
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", errors.New("no token given")
+	}
+	return token, nil
+}
+
+func init() {
+	tokenCmd.Flags().Bool("stdin", false, "Read the token from stdin")
+	authCmd.AddCommand(tokenCmd)
+}
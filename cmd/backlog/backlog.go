@@ -0,0 +1,174 @@
+package backlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd"
+	"github.com/iucario/bangumi-go/cmd/calendar"
+	"github.com/iucario/bangumi-go/cmd/watch"
+	"github.com/spf13/cobra"
+)
+
+var backlogCmd = &cobra.Command{
+	Use:   "backlog",
+	Short: "List watching anime you are behind on and stale entries",
+	Long: `List the anime in your watching list with aired episodes you have not watched,
+with the runtime left to catch up. Entries not updated in --stale days are marked
+as stale, consider moving them to on-hold.`,
+	Example: `bgm backlog
+bgm backlog --stale 60 --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		staleDays, _ := cmd.Flags().GetInt("stale")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		authClient := api.NewAuthClientWithConfig()
+		user := api.NewUser(authClient)
+		if user == nil {
+			api.AbortOnError(errors.New("failed to get user info, please login"))
+		}
+		watched, failed, err := calendar.FetchWatched(authClient, user.Username)
+		api.AbortOnError(err)
+		for _, id := range failed {
+			fmt.Fprintf(os.Stderr, "failed to get episodes of subject %d\n", id)
+		}
+		items := Build(watched, time.Now(), StaleAfter(staleDays))
+
+		if asJSON {
+			b, err := json.MarshalIndent(items, "", "  ")
+			api.AbortOnError(err)
+			fmt.Println(string(b))
+			return
+		}
+		printItems(items, staleDays)
+	},
+}
+
+func init() {
+	backlogCmd.Flags().Int("stale", DefaultStaleDays, "Mark entries not updated in this many days as stale, 0 to disable")
+	backlogCmd.Flags().Bool("json", false, "Output JSON")
+	cmd.RootCmd.AddCommand(backlogCmd)
+}
+
+// Item is a watching subject with aired episodes not watched, or not updated for a long time
+type Item struct {
+	SubjectID int       `json:"subject_id"`
+	Name      string    `json:"name"`
+	Watched   int       `json:"watched"` // EpStatus
+	Aired     int       `json:"aired"`   // Latest aired main episode
+	Total     int       `json:"total"`   // Main episodes, aired or not
+	Behind    int       `json:"behind"`  // Aired main episodes after Watched
+	UpdatedAt time.Time `json:"updated_at"`
+	Stale     bool      `json:"stale"`
+	// Runtime of the episodes behind. Episodes without a duration count as DefaultEpisodeLength.
+	RemainingMinutes int `json:"remaining_minutes"`
+	// Episodes behind without a duration
+	Estimated  int                       `json:"estimated_episodes"`
+	Collection api.UserSubjectCollection `json:"-"`
+}
+
+// Remaining is the runtime of the episodes behind
+func (i Item) Remaining() time.Duration {
+	return time.Duration(i.RemainingMinutes) * time.Minute
+}
+
+// DefaultStaleDays is how many days without an update make an entry stale
+const DefaultStaleDays = 30
+
+// StaleAfter converts days to a duration. 0 days disables stale entries.
+func StaleAfter(days int) time.Duration {
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Build returns the subjects that are behind or stale by now, the most episodes behind first.
+// Entries not updated for longer than staleAfter are stale. staleAfter 0 disables stale entries.
+func Build(watched []calendar.Watched, now time.Time, staleAfter time.Duration) []Item {
+	var items []Item
+	for _, w := range watched {
+		c := w.Collection
+		item := Item{
+			SubjectID:  int(c.SubjectID),
+			Name:       c.Name(),
+			Watched:    int(c.EpStatus),
+			UpdatedAt:  c.UpdatedAt,
+			Stale:      staleAfter > 0 && now.Sub(c.UpdatedAt) > staleAfter,
+			Collection: c,
+		}
+		var remaining time.Duration
+		for _, ep := range w.Episodes {
+			if ep.Type != 0 {
+				continue
+			}
+			item.Total++
+			aired, ok := w.AirTime(ep)
+			if !ok || aired.After(now) {
+				continue
+			}
+			number := watch.EpisodeNumber(ep)
+			item.Aired = max(item.Aired, number)
+			if number <= item.Watched {
+				continue
+			}
+			item.Behind++
+			length, err := ep.GetDuration()
+			if err != nil || length <= 0 {
+				length = calendar.DefaultEpisodeLength
+				item.Estimated++
+			}
+			remaining += length
+		}
+		item.RemainingMinutes = int(remaining.Round(time.Minute) / time.Minute)
+		if item.Behind > 0 || item.Stale {
+			items = append(items, item)
+		}
+	}
+	slices.SortStableFunc(items, func(a, b Item) int {
+		if a.Behind != b.Behind {
+			return b.Behind - a.Behind
+		}
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	})
+	return items
+}
+
+// FormatDuration formats a runtime as hours and minutes, e.g. "3h20m"
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func printItems(items []Item, staleDays int) {
+	if len(items) == 0 {
+		fmt.Println("You are up to date.")
+		return
+	}
+	var total time.Duration
+	behind, stale := 0, 0
+	for _, item := range items {
+		remaining := FormatDuration(item.Remaining())
+		if item.Estimated > 0 {
+			remaining = "~" + remaining
+		}
+		mark := ""
+		if item.Stale {
+			mark = fmt.Sprintf("  stale since %s", item.UpdatedAt.Format("2006-01-02"))
+			stale++
+		}
+		fmt.Printf("%3d behind  %3d/%-3d %8s  %s%s\n", item.Behind, item.Watched, item.Aired, remaining, item.Name, mark)
+		total += item.Remaining()
+		behind += item.Behind
+	}
+	fmt.Printf("\n%d episodes behind, %s to catch up.\n", behind, FormatDuration(total))
+	if stale > 0 {
+		fmt.Printf("%d entries not updated in %d days. Move them to on-hold with: bgm sub status <subject_id> -s stashed\n", stale, staleDays)
+	}
+}
@@ -2,12 +2,17 @@ package calendar
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/iucario/bangumi-go/api"
 	"github.com/iucario/bangumi-go/cmd"
+	"github.com/iucario/bangumi-go/internal/config"
+	"github.com/iucario/bangumi-go/internal/ics"
 	"github.com/spf13/cobra"
 )
 
@@ -15,12 +20,20 @@ var calendarCmd = &cobra.Command{
 	Use:   "cal",
 	Short: "Show calendar",
 	Run: func(cmd *cobra.Command, args []string) {
+		if exportICS, _ := cmd.Flags().GetBool("ics"); exportICS {
+			printWatchingICS()
+			return
+		}
 		client := api.NewHTTPClient("")
 		calendars, err := GetCalendar(client)
 		if err != nil {
 			cmd.PrintErr(err)
 			return
 		}
+		if mine, _ := cmd.Flags().GetBool("mine"); mine {
+			printMine(calendars)
+			return
+		}
 
 		for _, cal := range calendars {
 			// Sort items by follower count
@@ -50,6 +63,71 @@ var calendarCmd = &cobra.Command{
 	},
 }
 
+// printMine lists subjects of the user airing today with unwatched aired episodes.
+// Today is in the timezone of the config, and late-night slots count for the day they air.
+func printMine(calendars []api.Calendar) {
+	cfg, err := config.Load()
+	api.AbortOnError(err)
+	loc, err := cfg.Location()
+	api.AbortOnError(err)
+
+	authClient := api.NewAuthClientWithConfig()
+	user := api.NewUser(authClient)
+	if user == nil {
+		api.AbortOnError(errors.New("failed to get user info, please login"))
+	}
+	collections, err := GetMineCollections(authClient, user.Username)
+	api.AbortOnError(err)
+
+	mine := Mine(calendars, collections)
+	ids := make([]int, len(mine))
+	for i, item := range mine {
+		ids[i] = item.ID
+	}
+	broadcasts := GetBroadcasts(authClient, ids)
+
+	now := time.Now().In(loc)
+	var items []MineItem
+	for _, item := range mine {
+		if AirWeekday(item.ID, item.Weekday, broadcasts, now, loc) == now.Weekday() {
+			items = append(items, item)
+		}
+	}
+	FetchLatest(authClient.HTTPClient, items)
+
+	behind := 0
+	for _, item := range items {
+		if !item.Behind() {
+			continue
+		}
+		behind++
+		airTime := "--:--"
+		if b, ok := broadcasts[item.ID]; ok && b.HasTime() {
+			airTime = b.Next(now).In(loc).Format("15:04")
+		}
+		fmt.Printf("%s %7s %-8s %s\n", airTime, item.Progress(), item.Collection.GetStatus(), item.GetName())
+	}
+	if behind == 0 {
+		fmt.Printf("Nothing to catch up on today. %d of your subjects air today.\n", len(items))
+	}
+}
+
+// printWatchingICS writes upcoming episodes of the watching list as iCalendar to stdout
+func printWatchingICS() {
+	authClient := api.NewAuthClientWithConfig()
+	user := api.NewUser(authClient)
+	if user == nil {
+		api.AbortOnError(errors.New("failed to get user info, please login"))
+	}
+	now := time.Now()
+	cal, failed, err := WatchingCalendar(authClient, user.Username, now)
+	api.AbortOnError(err)
+	for _, id := range failed {
+		fmt.Fprintf(os.Stderr, "failed to get episodes of subject %d\n", id)
+	}
+	api.AbortOnError(ics.Write(os.Stdout, cal, now))
+}
+
 func GetCalendar(client *api.HTTPClient) ([]api.Calendar, error) {
 	url := "https://api.bgm.tv/calendar" // Calendar API endpoint has no '/v0'
 	bytes, err := client.Get(url)
@@ -64,5 +142,7 @@ func GetCalendar(client *api.HTTPClient) ([]api.Calendar, error) {
 }
 
 func init() {
+	calendarCmd.Flags().Bool("ics", false, "Write upcoming episodes of your watching list as iCalendar, e.g. --ics > schedule.ics")
+	calendarCmd.Flags().Bool("mine", false, "List subjects airing today in your watching and wish lists with unwatched episodes")
 	cmd.RootCmd.AddCommand(calendarCmd)
 }
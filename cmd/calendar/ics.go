@@ -0,0 +1,101 @@
+package calendar
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd/subject"
+	"github.com/iucario/bangumi-go/internal/ics"
+)
+
+// DefaultEpisodeLength is the length of episodes without a duration
+const DefaultEpisodeLength = 24 * time.Minute
+
+// EpisodeEvent returns the event of an episode. With a broadcast time it starts at that time
+// of the air date in JST, else it is an all-day event. It reports false without an air date.
+// The UID is the episode ID so that importing again updates the event.
+func EpisodeEvent(sbj api.SlimSubject, ep api.Episode, b *api.Broadcast) (ics.Event, bool) {
+	airdate, err := ep.GetAirTime()
+	if err != nil {
+		return ics.Event{}, false
+	}
+	number := ep.Ep
+	if number == 0 {
+		number = ep.Sort
+	}
+	summary := fmt.Sprintf("%s %d", sbj.GetName(), number)
+	if name := ep.NameCn + ep.Name; name != "" {
+		summary += " " + ep.GetName()
+	}
+	event := ics.Event{
+		UID:         fmt.Sprintf("bgm-episode-%d@bangumi-go", ep.ID),
+		Summary:     summary,
+		Description: fmt.Sprintf("%s\nhttps://bgm.tv/ep/%d", sbj.Name, ep.ID),
+		URL:         fmt.Sprintf("https://bgm.tv/subject/%d", sbj.ID),
+	}
+	if b == nil || !b.HasTime() {
+		event.AllDay = true
+		event.Start = airdate
+		event.End = airdate.AddDate(0, 0, 1)
+		return event, true
+	}
+	length, err := ep.GetDuration()
+	if err != nil || length <= 0 {
+		length = DefaultEpisodeLength
+	}
+	// Late-night times like 25:30 are after the end of the air date
+	event.Start = airdate.Add(time.Duration(b.Minutes) * time.Minute)
+	event.End = event.Start.Add(length)
+	return event, true
+}
+
+// episodeEvents returns events of main episodes airing on or after the date of from
+func episodeEvents(sbj api.SlimSubject, episodes []api.Episode, b *api.Broadcast, from time.Time) []ics.Event {
+	day := from.In(api.JST).Format("2006-01-02")
+	var events []ics.Event
+	for _, ep := range episodes {
+		if ep.Type != 0 || ep.Airdate < day {
+			continue
+		}
+		if event, ok := EpisodeEvent(sbj, ep, b); ok {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// SubjectCalendar returns the main episodes of a subject airing on or after from.
+// Use the zero time for all episodes.
+func SubjectCalendar(client *api.HTTPClient, subjectID int, from time.Time) (ics.Calendar, error) {
+	sbj, err := subject.GetSubjectInfo(client, subjectID)
+	if err != nil {
+		return ics.Calendar{}, err
+	}
+	episodes, err := subject.GetAllEpisodes(client, subjectID)
+	if err != nil {
+		return ics.Calendar{}, err
+	}
+	var broadcast *api.Broadcast
+	if b, ok := sbj.ParseBroadcast(); ok {
+		broadcast = &b
+	}
+	return ics.Calendar{
+		Name:   sbj.GetName(),
+		Events: episodeEvents(sbj.SlimSubject, episodes, broadcast, from),
+	}, nil
+}
+
+// WatchingCalendar returns the episodes of the anime in the watching list airing on or after from.
+// Subjects whose episodes fail to load are left out and returned in failed.
+func WatchingCalendar(client *api.AuthClient, username string, from time.Time) (cal ics.Calendar, failed []int, err error) {
+	watched, failed, err := FetchWatched(client, username)
+	if err != nil {
+		return ics.Calendar{}, nil, err
+	}
+	cal = ics.Calendar{Name: "Bangumi " + username}
+	for _, w := range watched {
+		cal.Events = append(cal.Events, episodeEvents(w.Collection.Subject, w.Episodes, w.Broadcast, from)...)
+	}
+	return cal, failed, nil
+}
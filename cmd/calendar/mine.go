@@ -0,0 +1,98 @@
+package calendar
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd/list"
+	"github.com/iucario/bangumi-go/cmd/subject"
+	"github.com/iucario/bangumi-go/internal/task"
+)
+
+// MineStatuses are the collections matched with the calendar
+var MineStatuses = []api.CollectionStatus{api.Watching, api.Wish}
+
+// MineItem is an airing subject in the watching or wish collection of the user
+type MineItem struct {
+	api.CalendarItem
+	Weekday    uint32 // 1 is Monday
+	Collection api.UserSubjectCollection
+	Latest     int // Latest aired episode, -1 if unknown
+}
+
+// Behind reports whether an aired episode is not watched yet
+func (m MineItem) Behind() bool {
+	return m.Latest > 0 && int(m.Collection.EpStatus) < m.Latest
+}
+
+// Progress is the watched and the latest aired episode, e.g. "3/5"
+func (m MineItem) Progress() string {
+	latest := "?"
+	if m.Latest >= 0 {
+		latest = strconv.Itoa(m.Latest)
+	}
+	return fmt.Sprintf("%d/%s", m.Collection.EpStatus, latest)
+}
+
+// GetMineCollections fetches the anime of a user in the MineStatuses, by subject ID
+func GetMineCollections(client *api.AuthClient, username string) (map[int]api.UserSubjectCollection, error) {
+	collections := make(map[int]api.UserSubjectCollection)
+	for _, status := range MineStatuses {
+		items, err := list.ListAllUserCollection(client, list.UserListOptions{
+			Username:       username,
+			SubjectType:    "anime",
+			CollectionType: status,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range items {
+			collections[int(c.SubjectID)] = c
+		}
+	}
+	return collections, nil
+}
+
+// Mine returns the calendar items in the collections in calendar order.
+// Latest episodes are unknown until FetchLatest.
+func Mine(calendars []api.Calendar, collections map[int]api.UserSubjectCollection) []MineItem {
+	var items []MineItem
+	for _, cal := range calendars {
+		for _, item := range cal.Items {
+			if c, ok := collections[item.ID]; ok {
+				items = append(items, MineItem{CalendarItem: item, Weekday: cal.Weekday.ID, Collection: c, Latest: -1})
+			}
+		}
+	}
+	return items
+}
+
+// FetchLatest sets the latest aired episode of the items concurrently.
+// Items whose episodes fail to load stay unknown.
+func FetchLatest(client *api.HTTPClient, items []MineItem) {
+	tasks := make([]task.Task, len(items))
+	for i, item := range items {
+		tasks[i] = task.Task{
+			ID: strconv.Itoa(i),
+			Do: func() (any, error) {
+				return subject.GetEpisodes(client, item.ID, 0, 100)
+			},
+		}
+	}
+	for id, res := range task.Run(tasks) {
+		i, _ := strconv.Atoi(id)
+		if episodes, ok := res.Data.(*api.Episodes); ok && res.Error == nil {
+			items[i].Latest = episodes.Latest()
+		}
+	}
+}
+
+// WeekdayID returns the calendar weekday of t. 1 is Monday and 7 is Sunday.
+func WeekdayID(t time.Time) uint32 {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return uint32(t.Weekday())
+}
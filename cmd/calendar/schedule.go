@@ -0,0 +1,120 @@
+package calendar
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd/subject"
+	"github.com/iucario/bangumi-go/util"
+)
+
+// scheduleTTL is how long a cached broadcast slot is used before it is fetched again
+const scheduleTTL = 7 * 24 * time.Hour
+
+// scheduleWorkers limits concurrent requests of subject details
+const scheduleWorkers = 8
+
+// cachedBroadcast is a slot in the schedule cache. Subjects without a slot are cached too.
+type cachedBroadcast struct {
+	Broadcast api.Broadcast `json:"broadcast"`
+	Known     bool          `json:"known"`
+	Fetched   time.Time     `json:"fetched"`
+}
+
+// schedulePath is the cache of broadcast slots by subject ID
+func schedulePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(util.ConfigDir(), "cache", "schedule.json")
+	}
+	return filepath.Join(dir, "bangumi-go", "schedule.json")
+}
+
+func loadSchedule() map[int]cachedBroadcast {
+	cache := make(map[int]cachedBroadcast)
+	b, err := os.ReadFile(schedulePath())
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(b, &cache); err != nil {
+		slog.Warn("invalid schedule cache", "error", err)
+	}
+	return cache
+}
+
+func saveSchedule(cache map[int]cachedBroadcast) error {
+	b, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(schedulePath()), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(schedulePath(), b, 0o644)
+}
+
+// GetBroadcasts returns the broadcast slots of subjects parsed from their infoboxes.
+// Slots are cached for a week. Subjects without a slot or failing to load are left out.
+func GetBroadcasts(client api.Client, ids []int) map[int]api.Broadcast {
+	cache := loadSchedule()
+	var missing []int
+	for _, id := range ids {
+		if c, ok := cache[id]; !ok || time.Since(c.Fetched) > scheduleTTL {
+			missing = append(missing, id)
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, scheduleWorkers)
+	for _, id := range missing {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			sbj, err := subject.GetSubjectInfo(client, id)
+			if err != nil {
+				slog.Error("failed to get broadcast", "subject", id, "error", err)
+				return
+			}
+			b, known := sbj.ParseBroadcast()
+			mu.Lock()
+			cache[id] = cachedBroadcast{Broadcast: b, Known: known, Fetched: time.Now()}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(missing) > 0 {
+		if err := saveSchedule(cache); err != nil {
+			slog.Error("failed to save schedule cache", "error", err)
+		}
+	}
+
+	broadcasts := make(map[int]api.Broadcast, len(ids))
+	for _, id := range ids {
+		if c, ok := cache[id]; ok && c.Known {
+			broadcasts[id] = c.Broadcast
+		}
+	}
+	return broadcasts
+}
+
+// AirWeekday returns the weekday of the next broadcast of a subject in loc.
+// A slot without a time keeps its weekday in JST, as the day in loc is not known.
+// weekday is the calendar weekday, 1 is Monday. It is used if the slot is unknown.
+func AirWeekday(id int, weekday uint32, broadcasts map[int]api.Broadcast, now time.Time, loc *time.Location) time.Weekday {
+	b, ok := broadcasts[id]
+	if !ok {
+		return time.Weekday(weekday % 7)
+	}
+	if !b.HasTime() {
+		return b.Weekday
+	}
+	return b.Next(now).In(loc).Weekday()
+}
@@ -0,0 +1,65 @@
+package calendar
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd/list"
+	"github.com/iucario/bangumi-go/cmd/subject"
+	"github.com/iucario/bangumi-go/internal/task"
+)
+
+// Watched is a subject in the watching list with its episodes
+type Watched struct {
+	Collection api.UserSubjectCollection
+	Episodes   []api.Episode
+	Broadcast  *api.Broadcast // nil if the broadcast time is unknown
+}
+
+// FetchWatched fetches the anime in the watching list of the user with their episodes.
+// Subjects whose episodes fail to load are left out and returned in failed.
+func FetchWatched(client *api.AuthClient, username string) (watched []Watched, failed []int, err error) {
+	collections, err := list.ListAllUserCollection(client, list.UserListOptions{
+		Username:       username,
+		SubjectType:    "anime",
+		CollectionType: api.Watching,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]int, len(collections))
+	tasks := make([]task.Task, len(collections))
+	for i, c := range collections {
+		ids[i] = int(c.SubjectID)
+		tasks[i] = task.Task{
+			ID: strconv.Itoa(i),
+			Do: func() (any, error) {
+				return subject.GetAllEpisodes(client.HTTPClient, int(c.SubjectID))
+			},
+		}
+	}
+	broadcasts := GetBroadcasts(client, ids)
+	results := task.Run(tasks)
+	for i, c := range collections {
+		res := results[strconv.Itoa(i)]
+		episodes, ok := res.Data.([]api.Episode)
+		if res.Error != nil || !ok {
+			failed = append(failed, ids[i])
+			continue
+		}
+		w := Watched{Collection: c, Episodes: episodes}
+		if b, ok := broadcasts[ids[i]]; ok {
+			w.Broadcast = &b
+		}
+		watched = append(watched, w)
+	}
+	return watched, failed, nil
+}
+
+// AirTime returns when an episode airs: the broadcast time on its air date if known,
+// else the start of the air date in JST. It reports false without an air date.
+func (w Watched) AirTime(ep api.Episode) (time.Time, bool) {
+	event, ok := EpisodeEvent(w.Collection.Subject, ep, w.Broadcast)
+	return event.Start, ok
+}
@@ -0,0 +1,234 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd"
+	"github.com/iucario/bangumi-go/cmd/list"
+	"github.com/iucario/bangumi-go/internal/task"
+	"github.com/spf13/cobra"
+)
+
+var compareCmd = &cobra.Command{
+	Use:     "compare <username> <username>",
+	Short:   "Compare the taste of two users",
+	Example: `bgm compare alice bob -s anime --format json`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		subjectType, _ := cmd.Flags().GetString("subject")
+		format, _ := cmd.Flags().GetString("format")
+		limit, _ := cmd.Flags().GetInt("limit")
+		minRate, _ := cmd.Flags().GetInt("min-rate")
+
+		authClient := api.NewAuthClientWithConfig()
+		collections, err := fetchCollections(authClient, args, subjectType)
+		api.AbortOnError(err)
+
+		result := Compare(args[0], collections[0], args[1], collections[1], limit, uint32(minRate))
+		if format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			api.AbortOnError(encoder.Encode(result))
+			return
+		}
+		fmt.Print(RenderTable(result))
+	},
+}
+
+func init() {
+	var subjectType string
+	var format string
+	var limit int
+	var minRate int
+	compareCmd.Flags().StringVarP(&subjectType, "subject", "s", "all",
+		"Subject type: book, anime, music, game, real, all.")
+	compareCmd.Flags().StringVarP(&format, "format", "f", "table", "Output format: table, json")
+	compareCmd.Flags().IntVarP(&limit, "limit", "n", 10, "Number of disagreements and recommendations to show")
+	compareCmd.Flags().IntVarP(&minRate, "min-rate", "r", 8, "Minimum rate of a recommended title")
+	cmd.RootCmd.AddCommand(compareCmd)
+}
+
+// fetchCollections fetches public collections of all users concurrently.
+func fetchCollections(c *api.AuthClient, usernames []string, subjectType string) ([][]api.UserSubjectCollection, error) {
+	tasks := make([]task.Task, len(usernames))
+	for i, username := range usernames {
+		tasks[i] = task.Task{
+			ID: strconv.Itoa(i),
+			Do: func() (any, error) {
+				return list.ListAllUserCollection(c, list.UserListOptions{
+					Username:       username,
+					SubjectType:    subjectType,
+					CollectionType: api.All,
+				})
+			},
+		}
+	}
+	res := task.Run(tasks)
+	collections := make([][]api.UserSubjectCollection, len(usernames))
+	for i, username := range usernames {
+		r := res[strconv.Itoa(i)]
+		if r.Error != nil {
+			return nil, fmt.Errorf("fetching collections of %s: %w", username, r.Error)
+		}
+		collections[i], _ = r.Data.([]api.UserSubjectCollection)
+	}
+	return collections, nil
+}
+
+// Title is a subject in a comparison with the rates of both users. 0 is unrated.
+type Title struct {
+	SubjectID uint32 `json:"subject_id"`
+	Name      string `json:"name"`
+	RateA     uint32 `json:"rate_a"`
+	RateB     uint32 `json:"rate_b"`
+}
+
+// Comparison is the taste comparison of user A and user B.
+type Comparison struct {
+	UserA         string  `json:"user_a"`
+	UserB         string  `json:"user_b"`
+	TotalA        int     `json:"total_a"`
+	TotalB        int     `json:"total_b"`
+	Overlap       int     `json:"overlap"`
+	Jaccard       float64 `json:"jaccard"`
+	BothRated     int     `json:"both_rated"`
+	Correlation   float64 `json:"correlation"` // Pearson correlation of rates. NaN is reported as 0
+	MeanDiff      float64 `json:"mean_diff"`   // Mean of RateA - RateB
+	Disagreements []Title `json:"disagreements"`
+	ForB          []Title `json:"for_b"` // Rated highly by A and not seen by B
+	ForA          []Title `json:"for_a"` // Rated highly by B and not seen by A
+}
+
+// Compare computes overlap, rating correlation, disagreements and recommendations.
+// A subject in wish list counts as not seen.
+func Compare(userA string, a []api.UserSubjectCollection, userB string, b []api.UserSubjectCollection, limit int, minRate uint32) *Comparison {
+	result := &Comparison{
+		UserA:         userA,
+		UserB:         userB,
+		TotalA:        len(a),
+		TotalB:        len(b),
+		Disagreements: []Title{},
+	}
+	collectionB := make(map[uint32]api.UserSubjectCollection, len(b))
+	for _, c := range b {
+		collectionB[c.SubjectID] = c
+	}
+	collectionA := make(map[uint32]api.UserSubjectCollection, len(a))
+	var ratesA, ratesB []float64
+	for _, ca := range a {
+		collectionA[ca.SubjectID] = ca
+		cb, ok := collectionB[ca.SubjectID]
+		if !ok {
+			continue
+		}
+		result.Overlap++
+		if ca.Rate > 0 && cb.Rate > 0 {
+			ratesA = append(ratesA, float64(ca.Rate))
+			ratesB = append(ratesB, float64(cb.Rate))
+			result.Disagreements = append(result.Disagreements, Title{
+				SubjectID: ca.SubjectID,
+				Name:      ca.Name(),
+				RateA:     ca.Rate,
+				RateB:     cb.Rate,
+			})
+		}
+	}
+	if union := len(a) + len(b) - result.Overlap; union > 0 {
+		result.Jaccard = float64(result.Overlap) / float64(union)
+	}
+	result.BothRated = len(ratesA)
+	result.Correlation = pearson(ratesA, ratesB)
+	for i := range ratesA {
+		result.MeanDiff += (ratesA[i] - ratesB[i]) / float64(len(ratesA))
+	}
+
+	slices.SortStableFunc(result.Disagreements, func(x, y Title) int {
+		return rateDiff(y) - rateDiff(x)
+	})
+	result.Disagreements = result.Disagreements[:min(limit, len(result.Disagreements))]
+	result.ForB = unseenTitles(a, collectionB, minRate, limit, true)
+	result.ForA = unseenTitles(b, collectionA, minRate, limit, false)
+	return result
+}
+
+// unseenTitles returns titles in from rated at least minRate that are not seen in other.
+func unseenTitles(from []api.UserSubjectCollection, other map[uint32]api.UserSubjectCollection, minRate uint32, limit int, fromA bool) []Title {
+	titles := []Title{}
+	for _, c := range from {
+		if c.Rate < minRate {
+			continue
+		}
+		if o, ok := other[c.SubjectID]; ok && o.GetStatus() != api.Wish {
+			continue
+		}
+		title := Title{SubjectID: c.SubjectID, Name: c.Name()}
+		if fromA {
+			title.RateA = c.Rate
+		} else {
+			title.RateB = c.Rate
+		}
+		titles = append(titles, title)
+	}
+	slices.SortStableFunc(titles, func(x, y Title) int {
+		return int(max(y.RateA, y.RateB)) - int(max(x.RateA, x.RateB))
+	})
+	return titles[:min(limit, len(titles))]
+}
+
+func rateDiff(t Title) int {
+	return int(math.Abs(float64(t.RateA) - float64(t.RateB)))
+}
+
+// pearson returns the Pearson correlation coefficient, or 0 if undefined.
+func pearson(x, y []float64) float64 {
+	n := float64(len(x))
+	if len(x) < 2 {
+		return 0
+	}
+	var sumX, sumY float64
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
+
+// RenderTable renders the comparison as plain text tables.
+func RenderTable(c *Comparison) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d  %s: %d\n", c.UserA, c.TotalA, c.UserB, c.TotalB)
+	fmt.Fprintf(&b, "Overlap: %d (Jaccard %.2f)\n", c.Overlap, c.Jaccard)
+	fmt.Fprintf(&b, "Both rated: %d. Correlation: %.2f. Mean difference: %+.2f\n", c.BothRated, c.Correlation, c.MeanDiff)
+
+	fmt.Fprintf(&b, "\nBiggest disagreements\n%5s %5s  %s\n", "A", "B", "Title")
+	for _, t := range c.Disagreements {
+		fmt.Fprintf(&b, "%5d %5d  %s\n", t.RateA, t.RateB, t.Name)
+	}
+	fmt.Fprintf(&b, "\n%s rated highly, %s has not seen\n", c.UserA, c.UserB)
+	for _, t := range c.ForB {
+		fmt.Fprintf(&b, "%5d %8d  %s\n", t.RateA, t.SubjectID, t.Name)
+	}
+	fmt.Fprintf(&b, "\n%s rated highly, %s has not seen\n", c.UserB, c.UserA)
+	for _, t := range c.ForA {
+		fmt.Fprintf(&b, "%5d %8d  %s\n", t.RateB, t.SubjectID, t.Name)
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package episode
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd"
+	"github.com/iucario/bangumi-go/cmd/calendar"
+	"github.com/iucario/bangumi-go/internal/ics"
+	"github.com/spf13/cobra"
+)
+
+var epCmd = &cobra.Command{
+	Use:   "ep",
+	Short: "Episode actions",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(`Available commands:
+bgm ep ics <subject_id> [--upcoming]`)
+	},
+}
+
+var icsCmd = &cobra.Command{
+	Use:   "ics <subject_id>",
+	Short: "Write episodes of a subject as iCalendar",
+	Long: `Write the main episodes of a subject with air dates as an iCalendar file to stdout.
+Episodes start at the broadcast time if the subject has one, else they are all-day events.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		subjectID, err := strconv.Atoi(args[0])
+		if err != nil {
+			api.AbortOnError(fmt.Errorf("invalid subject ID: %s", args[0]))
+		}
+		now := time.Now()
+		var from time.Time
+		if upcoming, _ := cmd.Flags().GetBool("upcoming"); upcoming {
+			from = now
+		}
+		cal, err := calendar.SubjectCalendar(api.NewHTTPClient(""), subjectID, from)
+		api.AbortOnError(err)
+		api.AbortOnError(ics.Write(os.Stdout, cal, now))
+	},
+}
+
+func init() {
+	icsCmd.Flags().Bool("upcoming", false, "Only episodes airing from today on")
+	epCmd.AddCommand(icsCmd)
+	cmd.RootCmd.AddCommand(epCmd)
+}
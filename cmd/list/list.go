@@ -2,6 +2,7 @@ package list
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/url"
@@ -22,19 +23,28 @@ var listCmd = &cobra.Command{
 			fmt.Printf("Invalid collection type: %s\n", collectionType)
 		}
 
-		authClient := api.NewAuthClientWithConfig()
-		user := api.NewUser(authClient)
-		userInfo, err := user.GetUserInfo()
-		api.AbortOnError(err)
+		username, _ := cmd.Flags().GetString("user")
+
+		// Public collections of other users do not need login
+		authClient := api.NewAuthClient("")
+		if username == "" {
+			authClient = api.NewAuthClientWithConfig()
+			user := api.NewUser(authClient)
+			if user == nil {
+				api.AbortOnError(errors.New("failed to get user info, please login or use --user"))
+			}
+			username = user.Username
+		}
 
 		options := UserListOptions{
 			SubjectType:    subjectType,
-			Username:       userInfo.Username,
+			Username:       username,
 			CollectionType: api.CollectionStatus(collectionType),
 			Limit:          30,
 			Offset:         0,
 		}
-		watchCollections, _ := ListUserCollection(authClient, options)
+		watchCollections, err := ListUserCollection(authClient, options)
+		api.AbortOnError(err)
 		slog.Info(fmt.Sprintf("collections in watching: %d\n", watchCollections.Total))
 
 		fmt.Printf("Total: %d. Showing: %d\n", watchCollections.Total, len(watchCollections.Data))
@@ -83,6 +93,29 @@ func ListUserCollection(authClient *api.AuthClient, options UserListOptions) (*a
 	return ListCollection(authClient, params)
 }
 
+// MaxPageSize is the largest limit the collections API accepts.
+const MaxPageSize = 50
+
+// ListAllUserCollection fetches every page of a user's collection.
+// Limit and Offset in options are ignored.
+func ListAllUserCollection(authClient *api.AuthClient, options UserListOptions) ([]api.UserSubjectCollection, error) {
+	options.Limit = MaxPageSize
+	options.Offset = 0
+	var collections []api.UserSubjectCollection
+	for {
+		page, err := ListUserCollection(authClient, options)
+		if err != nil {
+			return collections, err
+		}
+		collections = append(collections, page.Data...)
+		options.Offset += len(page.Data)
+		if len(page.Data) == 0 || options.Offset >= int(page.Total) {
+			break
+		}
+	}
+	return collections, nil
+}
+
 type ListParams struct {
 	Username       string `json:"username"`
 	SubjectType    int    `json:"subject_type"`
@@ -131,6 +164,8 @@ func ListCollection(authClient *api.AuthClient, params ListParams) (*api.UserCol
 func init() {
 	var subjectType string
 	var collectionType string
+	var username string
+	listCmd.Flags().StringVarP(&username, "user", "u", "", "List public collections of a user. Login is not required.")
 	listCmd.Flags().StringVarP(&collectionType, "collection", "c", "watch",
 		"Collection type: wish, done, watch, onhold, dropped, all.")
 	listCmd.Flags().StringVarP(&subjectType, "subject", "s", "all",
@@ -0,0 +1,299 @@
+package recommend
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/iucario/bangumi-go/api"
+)
+
+// StaffRoles are infobox keys used for matching and their names in reasons.
+var StaffRoles = map[string]string{
+	"动画制作": "studio",
+	"导演":   "director",
+	"原作":   "original work",
+	"开发":   "developer",
+	"出版社":  "publisher",
+	"作者":   "author",
+}
+
+// Weights of each kind of match in a score
+const (
+	tagWeight     = 1.0
+	wikiTagWeight = 1.5
+	staffWeight   = 2.0
+	qualityWeight = 0.5
+	friendWeight  = 0.3
+	priorVotes    = 100 // Votes of the prior in the bayesian score
+	priorScore    = 6.5
+	// Votes spread wider than this make the community score a poor guide.
+	// It is where Rating.Controversy starts to call a subject 莫衷一是.
+	divisiveStdDev = 2.0
+	divisiveWeight = 0.5
+)
+
+// Profile is the taste of a user learned from their collection.
+// Weights are normalized to [-1, 1]. Positive means the user likes it.
+type Profile struct {
+	Tags     map[string]float64
+	WikiTags map[string]float64
+	Staff    map[string]float64 // key is "role:name"
+	Seen     map[uint32]bool
+}
+
+// Candidate is an unseen subject with optional rates from friends.
+type Candidate struct {
+	Subject     api.Subject
+	FriendRates map[string]uint32
+}
+
+// Recommendation is a scored candidate with the reasons of the score.
+type Recommendation struct {
+	SubjectID uint32   `json:"subject_id"`
+	Name      string   `json:"name"`
+	Score     float64  `json:"score"`
+	Reasons   []string `json:"reasons"`
+}
+
+// preference is how much the user likes a subject in the collection.
+// A rate at the user's mean counts as mild liking.
+func preference(c api.UserSubjectCollection, meanRate float64) float64 {
+	if c.Rate > 0 {
+		return (float64(c.Rate)-meanRate)/2 + 0.5
+	}
+	switch c.GetStatus() {
+	case api.Done, api.Watching:
+		return 0.5
+	case api.Wish:
+		return 0.3
+	case api.Dropped:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// BuildProfile learns tag and staff weights from collections.
+// details are full subjects of some collections for wiki tags and staff. It can be nil.
+func BuildProfile(collections []api.UserSubjectCollection, details map[uint32]*api.Subject) *Profile {
+	p := &Profile{
+		Tags:     make(map[string]float64),
+		WikiTags: make(map[string]float64),
+		Staff:    make(map[string]float64),
+		Seen:     make(map[uint32]bool, len(collections)),
+	}
+	var rateSum float64
+	rated := 0
+	for _, c := range collections {
+		if c.Rate > 0 {
+			rateSum += float64(c.Rate)
+			rated++
+		}
+	}
+	meanRate := 7.0
+	if rated > 0 {
+		meanRate = rateSum / float64(rated)
+	}
+
+	for _, c := range collections {
+		p.Seen[c.SubjectID] = c.GetStatus() != api.Wish
+		w := preference(c, meanRate)
+		for _, tag := range c.Subject.Tags {
+			p.Tags[tag.Name] += w
+		}
+		for _, tag := range c.Tags {
+			p.Tags[tag] += w
+		}
+		subject, ok := details[c.SubjectID]
+		if !ok || subject == nil {
+			continue
+		}
+		for _, tag := range subject.WikiTags {
+			p.WikiTags[tag] += w
+		}
+		for _, key := range staffKeys(subject) {
+			p.Staff[key] += w
+		}
+	}
+	normalize(p.Tags)
+	normalize(p.WikiTags)
+	normalize(p.Staff)
+	return p
+}
+
+// staffKeys returns "role:name" of people and companies of a subject.
+func staffKeys(s *api.Subject) []string {
+	var keys []string
+	// Sorted so floating point sums are always added in the same order
+	roles := make([]string, 0, len(StaffRoles))
+	for infoKey := range StaffRoles {
+		roles = append(roles, infoKey)
+	}
+	sort.Strings(roles)
+	for _, infoKey := range roles {
+		for _, name := range s.InfoBoxNames(infoKey) {
+			keys = append(keys, infoKey+":"+name)
+		}
+	}
+	return keys
+}
+
+func normalize(weights map[string]float64) {
+	maxAbs := 0.0
+	for _, w := range weights {
+		maxAbs = max(maxAbs, math.Abs(w))
+	}
+	if maxAbs == 0 {
+		return
+	}
+	for k, w := range weights {
+		weights[k] = w / maxAbs
+	}
+}
+
+// BayesianScore shrinks the community score towards the prior when there are few votes.
+func BayesianScore(s *api.Subject) float64 {
+	votes := float64(s.Rating.Total)
+	return (s.Rating.Score*votes + priorScore*priorVotes) / (votes + priorVotes)
+}
+
+type match struct {
+	name   string
+	weight float64
+}
+
+// Score ranks a candidate with the profile and explains the score.
+// The result only depends on the inputs so it is deterministic.
+func (p *Profile) Score(c Candidate) Recommendation {
+	s := &c.Subject
+	rec := Recommendation{SubjectID: s.ID, Name: s.GetName(), Reasons: []string{}}
+
+	var tags []match
+	for _, tag := range s.Tags {
+		if w := p.Tags[tag.Name]; w != 0 {
+			tags = append(tags, match{tag.Name, w})
+		}
+	}
+	// Each tag list is capped so subjects with many tags do not win by count alone
+	if len(s.Tags) > 0 {
+		rec.Score += tagWeight * sumWeights(tags) / math.Sqrt(float64(len(s.Tags)))
+	}
+
+	var wikiTags []match
+	for _, tag := range s.WikiTags {
+		if w := p.WikiTags[tag]; w != 0 {
+			wikiTags = append(wikiTags, match{tag, w})
+		}
+	}
+	if len(s.WikiTags) > 0 {
+		rec.Score += wikiTagWeight * sumWeights(wikiTags) / math.Sqrt(float64(len(s.WikiTags)))
+	}
+
+	var staff []match
+	for _, key := range staffKeys(s) {
+		if w := p.Staff[key]; w != 0 {
+			staff = append(staff, match{key, w})
+		}
+	}
+	rec.Score += staffWeight * sumWeights(staff)
+
+	if s.Rating.Total > 0 {
+		rec.Score += qualityWeight * (BayesianScore(s) - priorScore)
+	}
+	// The vote distribution tells how much the score can be trusted
+	stdDev := s.Rating.StdDev()
+	if stdDev > divisiveStdDev {
+		rec.Score -= divisiveWeight * (stdDev - divisiveStdDev)
+	}
+
+	friends := make([]string, 0, len(c.FriendRates))
+	for friend := range c.FriendRates {
+		friends = append(friends, friend)
+	}
+	sort.Strings(friends)
+	for _, friend := range friends {
+		if rate := c.FriendRates[friend]; rate > 0 {
+			rec.Score += friendWeight * (float64(rate) - priorScore)
+		}
+	}
+
+	// Reasons
+	if names := positiveNames(append(tags, wikiTags...), 3); len(names) > 0 {
+		rec.Reasons = append(rec.Reasons, "shares tags "+strings.Join(names, ", "))
+	}
+	for _, name := range positiveNames(staff, 2) {
+		role, person, _ := strings.Cut(name, ":")
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("same %s %s", StaffRoles[role], person))
+	}
+	for _, friend := range friends {
+		if rate := c.FriendRates[friend]; rate > 0 {
+			rec.Reasons = append(rec.Reasons, fmt.Sprintf("%s rated %d", friend, rate))
+		} else {
+			rec.Reasons = append(rec.Reasons, fmt.Sprintf("in %s's collection", friend))
+		}
+	}
+	if s.Rating.Total > 0 {
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("score %.1f (%d votes)", s.Rating.Score, s.Rating.Total))
+	}
+	if stdDev > divisiveStdDev {
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("divisive, std dev %.1f", stdDev))
+	}
+	return rec
+}
+
+func sumWeights(matches []match) float64 {
+	sum := 0.0
+	for _, m := range matches {
+		sum += m.weight
+	}
+	return sum
+}
+
+// positiveNames returns names of the n best positive matches
+func positiveNames(matches []match, n int) []string {
+	matches = slices.Clone(matches)
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].weight != matches[j].weight {
+			return matches[i].weight > matches[j].weight
+		}
+		return matches[i].name < matches[j].name
+	})
+	var names []string
+	for _, m := range matches {
+		if m.weight <= 0 || len(names) >= n || slices.Contains(names, m.name) {
+			continue
+		}
+		names = append(names, m.name)
+	}
+	return names
+}
+
+// Rank scores unseen candidates and returns the best n. Ties are broken by subject ID.
+func (p *Profile) Rank(candidates []Candidate, n int) []Recommendation {
+	recs := make([]Recommendation, 0, len(candidates))
+	for _, c := range candidates {
+		if p.Seen[c.Subject.ID] {
+			continue
+		}
+		recs = append(recs, p.Score(c))
+	}
+	sort.SliceStable(recs, func(i, j int) bool {
+		if recs[i].Score != recs[j].Score {
+			return recs[i].Score > recs[j].Score
+		}
+		return recs[i].SubjectID < recs[j].SubjectID
+	})
+	return recs[:min(n, len(recs))]
+}
+
+// TopTags returns the n tags the user likes most.
+func (p *Profile) TopTags(n int) []string {
+	matches := make([]match, 0, len(p.Tags))
+	for name, w := range p.Tags {
+		matches = append(matches, match{name, w})
+	}
+	return positiveNames(matches, n)
+}
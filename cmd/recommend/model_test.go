@@ -0,0 +1,162 @@
+package recommend
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/iucario/bangumi-go/api"
+)
+
+func testCollection(id uint32, status api.CollectionStatus, rate uint32, tags ...string) api.UserSubjectCollection {
+	c := api.UserSubjectCollection{SubjectID: id, Rate: rate, Subject: api.SlimSubject{ID: id}}
+	c.SetStatus(status)
+	for _, tag := range tags {
+		c.Subject.Tags = append(c.Subject.Tags, api.Tag{Name: tag})
+	}
+	return c
+}
+
+func testSubject(id uint32, tags []string, infobox map[string]string) api.Subject {
+	s := api.Subject{SlimSubject: api.SlimSubject{ID: id, Name: "subject"}}
+	for _, tag := range tags {
+		s.Tags = append(s.Tags, api.Tag{Name: tag})
+	}
+	keys := make([]string, 0, len(infobox))
+	for key := range infobox {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		s.InfoBox = append(s.InfoBox, map[string]any{"key": key, "value": infobox[key]})
+	}
+	return s
+}
+
+// testProfile likes 原创 and 科幻 from Studio A, and dislikes 日常 and 后宫
+func testProfile() *Profile {
+	liked := testSubject(1, nil, map[string]string{"动画制作": "Studio A", "导演": "Director X"})
+	return BuildProfile([]api.UserSubjectCollection{
+		testCollection(1, api.Done, 9, "原创", "科幻"),
+		testCollection(2, api.Done, 5, "日常"),
+		testCollection(3, api.Dropped, 0, "后宫"),
+		testCollection(4, api.Wish, 0),
+	}, map[uint32]*api.Subject{1: &liked})
+}
+
+func TestBuildProfile(t *testing.T) {
+	p := testProfile()
+	tests := []struct {
+		name    string
+		weights map[string]float64
+		key     string
+		want    float64
+	}{
+		{"rated above mean", p.Tags, "原创", 1},
+		{"same preference", p.Tags, "科幻", 1},
+		{"rated below mean", p.Tags, "日常", -1.0 / 3},
+		{"dropped", p.Tags, "后宫", -2.0 / 3},
+		{"studio of detail", p.Staff, "动画制作:Studio A", 1},
+		{"director of detail", p.Staff, "导演:Director X", 1},
+		{"unknown", p.Tags, "百合", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.weights[tt.key]; math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("weight of %s = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+	for id, want := range map[uint32]bool{1: true, 3: true, 4: false, 5: false} {
+		if p.Seen[id] != want {
+			t.Errorf("Seen[%d] = %v, want %v", id, p.Seen[id], want)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	p := testProfile()
+	divisive := testSubject(14, nil, nil)
+	divisive.Rating = api.Rating{Total: 100, Score: 5.5, Count: api.Count{Field1: 50, Field10: 50}}
+	tests := []struct {
+		name      string
+		candidate Candidate
+		reasons   []string
+		positive  bool
+	}{
+		{
+			name:      "shared tags",
+			candidate: Candidate{Subject: testSubject(10, []string{"科幻", "原创", "日常"}, nil)},
+			reasons:   []string{"shares tags 原创, 科幻"},
+			positive:  true,
+		},
+		{
+			name:      "same studio",
+			candidate: Candidate{Subject: testSubject(11, nil, map[string]string{"动画制作": "Studio A"})},
+			reasons:   []string{"same studio Studio A"},
+			positive:  true,
+		},
+		{
+			name:      "disliked tags only",
+			candidate: Candidate{Subject: testSubject(12, []string{"后宫"}, nil)},
+			reasons:   []string{},
+		},
+		{
+			name: "friends",
+			candidate: Candidate{
+				Subject:     testSubject(13, nil, nil),
+				FriendRates: map[string]uint32{"bob": 0, "alice": 8},
+			},
+			reasons:  []string{"alice rated 8", "in bob's collection"},
+			positive: true,
+		},
+		{
+			name:      "divisive vote distribution",
+			candidate: Candidate{Subject: divisive},
+			reasons:   []string{"score 5.5 (100 votes)", "divisive, std dev 4.5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := p.Score(tt.candidate)
+			if !slices.Equal(rec.Reasons, tt.reasons) {
+				t.Errorf("reasons = %q, want %q", rec.Reasons, tt.reasons)
+			}
+			if (rec.Score > 0) != tt.positive {
+				t.Errorf("score = %v, want positive %v", rec.Score, tt.positive)
+			}
+			if again := p.Score(tt.candidate); again.Score != rec.Score {
+				t.Errorf("score changed from %v to %v", rec.Score, again.Score)
+			}
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	p := testProfile()
+	candidates := []Candidate{
+		{Subject: testSubject(30, []string{"原创"}, nil)},
+		{Subject: testSubject(20, []string{"原创"}, nil)},
+		{Subject: testSubject(1, []string{"原创", "科幻"}, nil)}, // seen
+		{Subject: testSubject(4, []string{"科幻"}, nil)},       // only wished
+		{Subject: testSubject(40, []string{"后宫"}, nil)},
+	}
+	tests := []struct {
+		n    int
+		want []uint32
+	}{
+		{5, []uint32{4, 20, 30, 40}},
+		{2, []uint32{4, 20}},
+		{0, []uint32{}},
+	}
+	for _, tt := range tests {
+		recs := p.Rank(candidates, tt.n)
+		got := make([]uint32, 0, len(recs))
+		for _, rec := range recs {
+			got = append(got, rec.SubjectID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Rank(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package recommend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd"
+	"github.com/iucario/bangumi-go/cmd/list"
+	"github.com/iucario/bangumi-go/cmd/search"
+	"github.com/iucario/bangumi-go/cmd/subject"
+	"github.com/spf13/cobra"
+)
+
+var recommendCmd = &cobra.Command{
+	Use:   "recommend",
+	Short: "Recommend unseen subjects from your collection",
+	Long: `Recommend unseen subjects with a content-based model.
+Your ratings and tags are matched against tags, wiki tags and staff of candidates
+from the search API and from collections of friends. Everything runs locally.`,
+	Example: `bgm recommend -s anime --from alice,bob`,
+	Run: func(cmd *cobra.Command, args []string) {
+		subjectType, _ := cmd.Flags().GetString("subject")
+		friends, _ := cmd.Flags().GetStringSlice("from")
+		limit, _ := cmd.Flags().GetInt("limit")
+		detailCount, _ := cmd.Flags().GetInt("details")
+		noSearch, _ := cmd.Flags().GetBool("no-search")
+		format, _ := cmd.Flags().GetString("format")
+
+		authClient := api.NewAuthClientWithConfig()
+		user := api.NewUser(authClient)
+		if user == nil {
+			api.AbortOnError(errors.New("failed to get user info, please login with `bgm auth login`"))
+		}
+		collections, err := list.ListAllUserCollection(authClient, list.UserListOptions{
+			Username:       user.Username,
+			SubjectType:    subjectType,
+			CollectionType: api.All,
+		})
+		api.AbortOnError(err)
+
+		details := FetchSubjects(authClient, topRatedIDs(collections, detailCount))
+		profile := BuildProfile(collections, details)
+
+		candidates := make(map[uint32]*Candidate)
+		if !noSearch {
+			searchCandidates(authClient, profile, subjectType, candidates)
+		}
+		for _, friend := range friends {
+			if err := friendCandidates(authClient, friend, subjectType, candidates); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to fetch collection of %s: %v\n", friend, err)
+			}
+		}
+
+		// Rank twice. The best candidates are fetched in full for wiki tags and staff.
+		recs := profile.Rank(candidateList(candidates), limit*2)
+		ids := make([]uint32, len(recs))
+		for i, rec := range recs {
+			ids[i] = rec.SubjectID
+		}
+		for id, s := range FetchSubjects(authClient, ids) {
+			candidates[id].Subject = *s
+		}
+		recs = profile.Rank(candidateList(candidates), limit)
+
+		if format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			api.AbortOnError(encoder.Encode(recs))
+			return
+		}
+		for i, rec := range recs {
+			fmt.Printf("%2d. %5.2f %8d %s\n", i+1, rec.Score, rec.SubjectID, rec.Name)
+			fmt.Printf("    %s\n", strings.Join(rec.Reasons, "; "))
+		}
+	},
+}
+
+func init() {
+	var subjectType string
+	var friends []string
+	var limit int
+	var detailCount int
+	var noSearch bool
+	var format string
+	recommendCmd.Flags().StringVarP(&subjectType, "subject", "s", "anime",
+		"Subject type: book, anime, music, game, real, all.")
+	recommendCmd.Flags().StringSliceVar(&friends, "from", nil, "Also recommend from collections of these users E.g. --from alice,bob")
+	recommendCmd.Flags().IntVarP(&limit, "limit", "n", 20, "Number of recommendations")
+	recommendCmd.Flags().IntVar(&detailCount, "details", 20, "Number of your top rated subjects fetched for wiki tags and staff")
+	recommendCmd.Flags().BoolVar(&noSearch, "no-search", false, "Do not search candidates. Use with --from")
+	recommendCmd.Flags().StringVarP(&format, "format", "f", "table", "Output format: table, json")
+	cmd.RootCmd.AddCommand(recommendCmd)
+}
+
+// searchCandidates searches the best ranked subjects of the user's favorite tags.
+func searchCandidates(c api.Client, profile *Profile, subjectType string, candidates map[uint32]*Candidate) {
+	filter := api.Filter{}
+	if t, ok := api.SubjectTypeMap[subjectType]; ok && t != api.SubjectType(0) {
+		filter.Type = []api.SubjectType{t}
+	}
+	for _, tag := range profile.TopTags(3) {
+		filter.Tag = []string{tag}
+		result, err := search.Search(c, api.Payload{Sort: api.RANK, Filter: filter}, 50, 0)
+		if err != nil {
+			slog.Error("searching candidates", "Tag", tag, "Error", err)
+			continue
+		}
+		for _, s := range result.Data {
+			if _, ok := candidates[s.ID]; !ok {
+				candidates[s.ID] = &Candidate{Subject: s, FriendRates: map[string]uint32{}}
+			}
+		}
+	}
+}
+
+// friendCandidates adds subjects a friend has finished or is watching.
+func friendCandidates(c *api.AuthClient, friend, subjectType string, candidates map[uint32]*Candidate) error {
+	collections, err := list.ListAllUserCollection(c, list.UserListOptions{
+		Username:       friend,
+		SubjectType:    subjectType,
+		CollectionType: api.All,
+	})
+	if err != nil {
+		return err
+	}
+	for _, collection := range collections {
+		status := collection.GetStatus()
+		if status != api.Done && status != api.Watching {
+			continue
+		}
+		candidate, ok := candidates[collection.SubjectID]
+		if !ok {
+			candidate = &Candidate{
+				Subject:     api.Subject{SlimSubject: collection.Subject},
+				FriendRates: map[string]uint32{},
+			}
+			candidates[collection.SubjectID] = candidate
+		}
+		candidate.FriendRates[friend] = collection.Rate
+	}
+	return nil
+}
+
+func candidateList(candidates map[uint32]*Candidate) []Candidate {
+	result := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		result = append(result, *c)
+	}
+	// Map order is random. Sort so ranking does not depend on it.
+	slices.SortFunc(result, func(a, b Candidate) int {
+		return int(a.Subject.ID) - int(b.Subject.ID)
+	})
+	return result
+}
+
+// topRatedIDs returns subject IDs of the n highest rated collections.
+func topRatedIDs(collections []api.UserSubjectCollection, n int) []uint32 {
+	rated := slices.Clone(collections)
+	slices.SortStableFunc(rated, func(a, b api.UserSubjectCollection) int {
+		if a.Rate != b.Rate {
+			return int(b.Rate) - int(a.Rate)
+		}
+		return int(a.SubjectID) - int(b.SubjectID)
+	})
+	ids := make([]uint32, 0, n)
+	for _, c := range rated[:min(n, len(rated))] {
+		if c.Rate > 0 {
+			ids = append(ids, c.SubjectID)
+		}
+	}
+	return ids
+}
+
+// FetchSubjects fetches full subjects concurrently. Failed subjects are left out.
+func FetchSubjects(c api.Client, ids []uint32) map[uint32]*api.Subject {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	subjects := make(map[uint32]*api.Subject, len(ids))
+	workers := make(chan struct{}, 4)
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id uint32) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			s, err := subject.GetSubjectInfo(c, int(id))
+			if err != nil {
+				slog.Error("fetching subject", "ID", id, "Error", err)
+				return
+			}
+			mu.Lock()
+			subjects[id] = s
+			mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return subjects
+}
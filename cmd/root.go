@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/internal/config"
 	"github.com/iucario/bangumi-go/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -16,6 +18,19 @@ var RootCmd = &cobra.Command{
 	Short: "bgm is a command line tool for Bangumi.tv",
 	Run: func(cmd *cobra.Command, args []string) {
 	},
+	// Select the profile before any command reads the config directory
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		profile, _ := cmd.Flags().GetString("profile")
+		if err := util.SetProfile(profile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		ConfigDir = util.ConfigDir()
+		// An invalid config is reported by the commands that use more of it
+		if cfg, err := config.Load(); err == nil {
+			api.CredentialStoreKind = cfg.CredentialStore
+		}
+	},
 }
 
 func Execute() {
@@ -33,5 +48,6 @@ func Execute() {
 }
 
 func init() {
+	RootCmd.PersistentFlags().String("profile", "", "Account profile to use, see bgm auth list. Default is $BGM_PROFILE or the switched profile")
 	ConfigDir = util.ConfigDir()
 }
@@ -0,0 +1,197 @@
+package season
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd"
+	"github.com/iucario/bangumi-go/cmd/search"
+	"github.com/spf13/cobra"
+)
+
+var seasonCmd = &cobra.Command{
+	Use:   "season [year] [winter|spring|summer|fall]",
+	Short: "List anime of a season grouped by platform",
+	Example: `bgm season
+bgm season 2026 fall -s score`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		season, err := ParseArgs(args, time.Now())
+		api.AbortOnError(err)
+		sortBy, _ := cmd.Flags().GetString("sort")
+		if sortBy != "heat" && sortBy != "score" {
+			api.AbortOnError(fmt.Errorf("invalid sort %q, must be heat or score", sortBy))
+		}
+
+		subjects, err := Fetch(api.NewAuthClientWithConfig(), season)
+		api.AbortOnError(err)
+		SortSubjects(subjects, sortBy)
+
+		fmt.Printf("%s: %d subjects\n", season, len(subjects))
+		for _, group := range GroupByPlatform(subjects) {
+			fmt.Printf("\n\033[1;36m%s\033[0m (%d)\n", group.Platform, len(group.Subjects))
+			for _, s := range group.Subjects {
+				fmt.Printf("%4.1f %6d  %s\n", s.Rating.Score, Heat(&s), s.GetName())
+			}
+		}
+	},
+}
+
+func init() {
+	seasonCmd.Flags().StringP("sort", "s", "heat", "Sort by: heat, score")
+	cmd.RootCmd.AddCommand(seasonCmd)
+}
+
+// Names of seasons by the first month of the season
+var Names = []string{"winter", "spring", "summer", "fall"}
+
+// Season is a quarter of a year in which anime start airing
+type Season struct {
+	Year  int
+	Index int // 0 winter (January to March), 1 spring, 2 summer, 3 fall
+}
+
+// Current returns the season of t
+func Current(t time.Time) Season {
+	return Season{Year: t.Year(), Index: (int(t.Month()) - 1) / 3}
+}
+
+// ParseSeason parses a season name. autumn is fall.
+func ParseSeason(name string) (int, error) {
+	name = strings.ToLower(name)
+	if name == "autumn" {
+		name = "fall"
+	}
+	index := slices.Index(Names, name)
+	if index < 0 {
+		return 0, fmt.Errorf("invalid season %q, must be one of %s", name, strings.Join(Names, ", "))
+	}
+	return index, nil
+}
+
+// ParseArgs reads the year and the season name. The current season is used for missing arguments.
+func ParseArgs(args []string, now time.Time) (Season, error) {
+	season := Current(now)
+	if len(args) > 0 {
+		year, err := strconv.Atoi(args[0])
+		if err != nil {
+			return season, fmt.Errorf("invalid year %q", args[0])
+		}
+		season.Year = year
+	}
+	if len(args) > 1 {
+		index, err := ParseSeason(args[1])
+		if err != nil {
+			return season, err
+		}
+		season.Index = index
+	}
+	return season, nil
+}
+
+func (s Season) String() string {
+	return fmt.Sprintf("%d %s", s.Year, Names[s.Index])
+}
+
+// Add returns the season n seasons later, or earlier if n is negative
+func (s Season) Add(n int) Season {
+	quarters := s.Year*4 + s.Index + n
+	return Season{Year: quarters / 4, Index: quarters % 4}
+}
+
+// Start is the first day of the season
+func (s Season) Start() time.Time {
+	return time.Date(s.Year, time.Month(s.Index*3+1), 1, 0, 0, 0, 0, api.JST)
+}
+
+// Filter searches anime first aired in the season
+func (s Season) Filter() api.Filter {
+	return api.Filter{
+		Type: []api.SubjectType{api.ANIME},
+		AirDate: []string{
+			">=" + s.Start().Format("2006-01-02"),
+			"<" + s.Add(1).Start().Format("2006-01-02"),
+		},
+	}
+}
+
+// pageSize of season searches
+const pageSize = 20
+
+// Fetch returns every anime of the season, the most popular first
+func Fetch(client api.Client, season Season) ([]api.Subject, error) {
+	payload := api.Payload{Sort: api.HEAT, Filter: season.Filter()}
+	var subjects []api.Subject
+	for {
+		page, err := search.Search(client, payload, pageSize, len(subjects))
+		if err != nil {
+			return subjects, err
+		}
+		subjects = append(subjects, page.Data...)
+		if len(page.Data) == 0 || len(subjects) >= page.Total {
+			return subjects, nil
+		}
+	}
+}
+
+// Heat is the number of users who collected a subject
+func Heat(s *api.Subject) uint32 {
+	c := s.CollectionCount
+	return c.Watching + c.Wish + c.Done + c.OnHold + c.Dropped
+}
+
+// SortSubjects sorts by "heat" or "score", the highest first
+func SortSubjects(subjects []api.Subject, by string) {
+	slices.SortStableFunc(subjects, func(a, b api.Subject) int {
+		if by == "score" && a.Rating.Score != b.Rating.Score {
+			if a.Rating.Score > b.Rating.Score {
+				return -1
+			}
+			return 1
+		}
+		return int(Heat(&b)) - int(Heat(&a))
+	})
+}
+
+// PlatformGroup is the subjects of a platform like TV, WEB or 剧场版
+type PlatformGroup struct {
+	Platform string
+	Subjects []api.Subject
+}
+
+// platformOrder puts the common platforms first. Other platforms follow by name.
+var platformOrder = []string{"TV", "WEB", "剧场版", "OVA"}
+
+// GroupByPlatform groups subjects by Platform keeping their order
+func GroupByPlatform(subjects []api.Subject) []PlatformGroup {
+	var groups []PlatformGroup
+	for _, s := range subjects {
+		platform := s.Platform
+		if platform == "" {
+			platform = "其他"
+		}
+		index := slices.IndexFunc(groups, func(g PlatformGroup) bool { return g.Platform == platform })
+		if index < 0 {
+			groups = append(groups, PlatformGroup{Platform: platform})
+			index = len(groups) - 1
+		}
+		groups[index].Subjects = append(groups[index].Subjects, s)
+	}
+	rank := func(platform string) int {
+		if i := slices.Index(platformOrder, strings.ToUpper(platform)); i >= 0 {
+			return i
+		}
+		return len(platformOrder)
+	}
+	slices.SortStableFunc(groups, func(a, b PlatformGroup) int {
+		if ra, rb := rank(a.Platform), rank(b.Platform); ra != rb {
+			return ra - rb
+		}
+		return strings.Compare(a.Platform, b.Platform)
+	})
+	return groups
+}
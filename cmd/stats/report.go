@@ -0,0 +1,209 @@
+package stats
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/iucario/bangumi-go/api"
+)
+
+type reportData struct {
+	Username string
+	*Stats
+	Statuses []api.CollectionStatus
+	Types    []api.SubjectType
+	Ratings  []int
+	// Bars of the Markdown rating table by rate
+	MineBars, CommunityBars [11]string
+}
+
+func newReportData(username string, s *Stats) reportData {
+	data := reportData{
+		Username: username,
+		Stats:    s,
+		Statuses: api.C_STATUS,
+		Types:    api.S_TYPE_ALL,
+		Ratings:  []int{10, 9, 8, 7, 6, 5, 4, 3, 2, 1},
+	}
+	data.MineBars, data.CommunityBars = s.RateBars(20)
+	return data
+}
+
+const markdownReport = `# {{.Username}}'s {{.Year}} on Bangumi
+
+- Subjects collected or updated: **{{.Total}}**
+- Episodes watched of these subjects, all time: **{{.Episodes}}**
+- Watch time of these subjects, all time: **{{printf "%.1f" .Hours}} hours**{{if .Estimated}} ({{.Estimated}} subjects estimated){{end}}
+- Completion rate: **{{printf "%.1f" (percent .CompletionRate)}}%**
+- Average rating: **{{printf "%.2f" .MeanRate}}** (community {{printf "%.2f" .MeanScore}})
+
+## Status
+
+| Status | Count |
+| --- | ---: |
+{{range .Statuses}}| {{.}} | {{index $.ByStatus .}} |
+{{end}}
+## Subject types
+
+| Type | Count |
+| --- | ---: |
+{{range .Types}}| {{.CN}} | {{index $.ByType .}} |
+{{end}}
+## Ratings
+
+| Rate | Mine | Community |
+| ---: | --- | --- |
+{{range .Ratings}}| {{.}} | {{with index $.MineBars .}}` + "`{{.}}`" + ` {{end}}{{index $.RateHistogram .}} | {{with index $.CommunityBars .}}` + "`{{.}}`" + ` {{end}}{{index $.ScoreHistogram .}} |
+{{end}}
+## Top rated
+{{range $i, $c := .TopRated}}
+{{add $i 1}}. [{{markdown $c.Name}}](https://bgm.tv/subject/{{$c.SubjectID}}) {{$c.Rate}}/10{{end}}
+
+## Top tags
+
+{{range .TopTags}}` + "`{{.Name}}`" + ` ×{{.Count}} {{end}}
+`
+
+const htmlReport = `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>{{.Username}}'s {{.Year}} on Bangumi</title>
+    <style>
+        body {
+            font-family: Arial, sans-serif;
+            background-color: #f5f5f5;
+            color: #333;
+            margin: 0;
+            padding: 40px 0;
+        }
+        .container {
+            max-width: 720px;
+            margin: 0 auto;
+            padding: 40px;
+            background-color: white;
+            border-radius: 8px;
+            box-shadow: 0 2px 10px rgba(0, 0, 0, 0.1);
+        }
+        h1, h2 {
+            color: #F09199;
+        }
+        .summary {
+            display: flex;
+            flex-wrap: wrap;
+            gap: 16px;
+        }
+        .summary div {
+            flex: 1 1 150px;
+            padding: 12px;
+            background-color: #fdf0f1;
+            border-radius: 8px;
+        }
+        .summary strong {
+            display: block;
+            font-size: 24px;
+        }
+        table {
+            width: 100%;
+            border-collapse: collapse;
+        }
+        td {
+            padding: 2px 6px;
+        }
+        .bar {
+            display: inline-block;
+            height: 12px;
+            background-color: #F09199;
+        }
+        .bar.community {
+            background-color: #6d77b3;
+        }
+        .tag {
+            display: inline-block;
+            margin: 2px;
+            padding: 2px 8px;
+            border-radius: 4px;
+            background-color: #eee;
+        }
+    </style>
+</head>
+<body>
+<div class="container">
+    <h1>{{.Username}}'s {{.Year}} on Bangumi</h1>
+    <div class="summary">
+        <div><strong>{{.Total}}</strong>subjects</div>
+        <div><strong>{{.Episodes}}</strong>episodes of these subjects, all time</div>
+        <div><strong>{{printf "%.1f" .Hours}}</strong>hours of these subjects, all time</div>
+        <div><strong>{{printf "%.1f" (percent .CompletionRate)}}%</strong>completed</div>
+    </div>
+
+    <h2>Status</h2>
+    <table>
+    {{range .Statuses}}<tr><td>{{.}}</td><td>{{index $.ByStatus .}}</td></tr>
+    {{end}}</table>
+
+    <h2>Subject types</h2>
+    <table>
+    {{range .Types}}<tr><td>{{.CN}}</td><td>{{index $.ByType .}}</td></tr>
+    {{end}}</table>
+
+    <h2>Ratings</h2>
+    <p>Mine {{printf "%.2f" .MeanRate}} / community {{printf "%.2f" .MeanScore}}</p>
+    <table>
+    {{$max := .RateMax}}{{range .Ratings}}<tr>
+        <td>{{.}}</td>
+        <td><span class="bar" style="width: {{width (index $.RateHistogram .) $max}}px"></span> {{index $.RateHistogram .}}</td>
+        <td><span class="bar community" style="width: {{width (index $.ScoreHistogram .) $max}}px"></span> {{index $.ScoreHistogram .}}</td>
+    </tr>
+    {{end}}</table>
+
+    <h2>Top rated</h2>
+    <ol>
+    {{range .TopRated}}<li><a href="https://bgm.tv/subject/{{.SubjectID}}">{{.Name}}</a> {{.Rate}}/10</li>
+    {{end}}</ol>
+
+    <h2>Top tags</h2>
+    <p>{{range .TopTags}}<span class="tag">{{.Name}} ×{{.Count}}</span>{{end}}</p>
+</div>
+</body>
+</html>
+`
+
+var templateFuncs = map[string]any{
+	"add":     func(a, b int) int { return a + b },
+	"percent": func(f float64) float64 { return f * 100 },
+	// width of a HTML bar in pixels
+	"width": func(value, maxValue int) int {
+		if maxValue == 0 {
+			return 0
+		}
+		return value * 200 / maxValue
+	},
+	"markdown": markdownEscaper.Replace,
+}
+
+// markdownEscaper escapes titles, so brackets, stars and underscores in them do not break links
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "|", `\|`,
+)
+
+// WriteMarkdown writes a year-in-review report in Markdown.
+func WriteMarkdown(w io.Writer, username string, s *Stats) error {
+	t, err := template.New("markdown").Funcs(templateFuncs).Parse(markdownReport)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, newReportData(username, s))
+}
+
+// WriteHTML writes a year-in-review report as a standalone HTML page.
+func WriteHTML(w io.Writer, username string, s *Stats) error {
+	t, err := htmltemplate.New("html").Funcs(templateFuncs).Parse(htmlReport)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, newReportData(username, s))
+}
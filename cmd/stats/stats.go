@@ -0,0 +1,391 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd"
+	"github.com/iucario/bangumi-go/cmd/list"
+	"github.com/iucario/bangumi-go/cmd/subject"
+	"github.com/iucario/bangumi-go/util"
+	"github.com/spf13/cobra"
+)
+
+// DefaultEpisodeDuration is used when an episode has no duration.
+var DefaultEpisodeDuration = map[api.SubjectType]time.Duration{
+	api.ANIME: 24 * time.Minute,
+	api.REAL:  45 * time.Minute,
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Collection statistics",
+	Example: `bgm stats
+bgm stats --year 2026 --format html > 2026.html`,
+	Run: func(cmd *cobra.Command, args []string) {
+		year, _ := cmd.Flags().GetInt("year")
+		format, _ := cmd.Flags().GetString("format")
+		estimate, _ := cmd.Flags().GetBool("estimate")
+
+		authClient := api.NewAuthClientWithConfig()
+		user := api.NewUser(authClient)
+		if user == nil {
+			api.AbortOnError(errors.New("failed to get user info, please login with `bgm auth login`"))
+		}
+
+		collections, err := list.ListAllUserCollection(authClient, list.UserListOptions{
+			Username:       user.Username,
+			SubjectType:    "all",
+			CollectionType: api.All,
+		})
+		api.AbortOnError(err)
+		if year > 0 {
+			collections = FilterYear(collections, year)
+		}
+
+		var durations map[uint32]time.Duration
+		if !estimate {
+			fmt.Fprintf(os.Stderr, "Fetching episodes of %d subjects...\n", len(collections))
+			durations = WatchDurations(authClient.HTTPClient, collections)
+		}
+		stats := Compute(collections, durations, year)
+
+		switch {
+		case year == 0 || format == "text":
+			fmt.Print(RenderText(stats))
+		case format == "html":
+			err = WriteHTML(os.Stdout, user.Username, stats)
+		default:
+			err = WriteMarkdown(os.Stdout, user.Username, stats)
+		}
+		api.AbortOnError(err)
+	},
+}
+
+func init() {
+	var year int
+	var format string
+	var estimate bool
+	statsCmd.Flags().IntVarP(&year, "year", "y", 0, "Year in review of collections updated in [year]. Episodes and watch time are all-time totals of them")
+	statsCmd.Flags().StringVarP(&format, "format", "f", "markdown", "Year in review format: markdown, html, text")
+	statsCmd.Flags().BoolVarP(&estimate, "estimate", "e", false,
+		"Estimate watch time from default episode lengths instead of fetching episodes")
+	cmd.RootCmd.AddCommand(statsCmd)
+}
+
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+// Stats holds the distributions over a user's collection.
+type Stats struct {
+	Year            int // 0 for the whole collection
+	Total           int
+	ByStatus        map[api.CollectionStatus]int
+	ByType          map[api.SubjectType]int
+	RateHistogram   [11]int // index is our rate. 0 for unrated
+	ScoreHistogram  [11]int // community score rounded, of subjects we rated
+	RatedCount      int
+	MeanRate        float64
+	MeanScore       float64 // community score of subjects we rated
+	TopTags         []TagCount
+	TopSubjectTags  []TagCount
+	CompletionRate  float64       // done / (done + watching + on hold + dropped)
+	Episodes        int           // with Year, all-time progress of the subjects updated in the year
+	EpisodesPerWeek float64       // of the whole collection only, 0 with Year
+	WatchTime       time.Duration // of the watched episodes, all-time like Episodes
+	Estimated       int           // subjects with estimated watch time
+	TopRated        []api.UserSubjectCollection
+}
+
+// FilterYear returns collections updated in year.
+func FilterYear(collections []api.UserSubjectCollection, year int) []api.UserSubjectCollection {
+	filtered := make([]api.UserSubjectCollection, 0, len(collections))
+	for _, c := range collections {
+		if c.UpdatedAt.Year() == year {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// Compute calculates statistics of collections.
+// durations maps subject ID to watched time. Missing subjects are estimated with DefaultEpisodeDuration.
+func Compute(collections []api.UserSubjectCollection, durations map[uint32]time.Duration, year int) *Stats {
+	s := &Stats{
+		Year:     year,
+		Total:    len(collections),
+		ByStatus: make(map[api.CollectionStatus]int),
+		ByType:   make(map[api.SubjectType]int),
+	}
+	tags := make(map[string]int)
+	subjectTags := make(map[string]int)
+	var rateSum, scoreSum float64
+	earliest := time.Now()
+	for _, c := range collections {
+		s.ByStatus[c.GetStatus()]++
+		s.ByType[api.SubjectType(c.SubjectType)]++
+		if c.UpdatedAt.Before(earliest) {
+			earliest = c.UpdatedAt
+		}
+		if c.Rate > 0 && c.Rate <= 10 {
+			s.RateHistogram[c.Rate]++
+			s.RatedCount++
+			rateSum += float64(c.Rate)
+			if c.Subject.Score > 0 {
+				s.ScoreHistogram[int(math.Round(c.Subject.Score))]++
+				scoreSum += c.Subject.Score
+			}
+		} else {
+			s.RateHistogram[0]++
+		}
+		for _, tag := range c.Tags {
+			tags[tag]++
+		}
+		for _, tag := range c.Subject.Tags {
+			subjectTags[tag.Name]++
+		}
+
+		subjectType := api.SubjectType(c.SubjectType)
+		if _, ok := DefaultEpisodeDuration[subjectType]; !ok || c.EpStatus == 0 {
+			continue
+		}
+		s.Episodes += int(c.EpStatus)
+		if d, ok := durations[c.SubjectID]; ok {
+			s.WatchTime += d
+		} else {
+			s.WatchTime += time.Duration(c.EpStatus) * DefaultEpisodeDuration[subjectType]
+			s.Estimated++
+		}
+	}
+	if s.RatedCount > 0 {
+		s.MeanRate = rateSum / float64(s.RatedCount)
+		s.MeanScore = scoreSum / float64(s.RatedCount)
+	}
+	started := s.ByStatus[api.Done] + s.ByStatus[api.Watching] + s.ByStatus[api.OnHold] + s.ByStatus[api.Dropped]
+	if started > 0 {
+		s.CompletionRate = float64(s.ByStatus[api.Done]) / float64(started)
+	}
+
+	// All-time episodes over the weeks of a year would overstate the rate
+	if year == 0 {
+		weeks := max(1, time.Since(earliest).Hours()/24/7)
+		s.EpisodesPerWeek = float64(s.Episodes) / weeks
+	}
+
+	s.TopTags = topTags(tags, 10)
+	s.TopSubjectTags = topTags(subjectTags, 10)
+	s.TopRated = topRated(collections, 10)
+	return s
+}
+
+// WatchDurations fetches episodes of anime and real subjects and sums the duration of watched episodes.
+func WatchDurations(c *api.HTTPClient, collections []api.UserSubjectCollection) map[uint32]time.Duration {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	durations := make(map[uint32]time.Duration)
+	workers := make(chan struct{}, 4)
+	for _, collection := range collections {
+		subjectType := api.SubjectType(collection.SubjectType)
+		if _, ok := DefaultEpisodeDuration[subjectType]; !ok || collection.EpStatus == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(collection api.UserSubjectCollection) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			episodes, err := subject.GetEpisodes(c, int(collection.SubjectID), 0, 100)
+			if err != nil {
+				return
+			}
+			d := watchedDuration(episodes.Data, int(collection.EpStatus), DefaultEpisodeDuration[subjectType])
+			mu.Lock()
+			durations[collection.SubjectID] = d
+			mu.Unlock()
+		}(collection)
+	}
+	wg.Wait()
+	return durations
+}
+
+// watchedDuration sums the first watched main episodes. Episodes without duration count as fallback.
+func watchedDuration(episodes []api.Episode, watched int, fallback time.Duration) time.Duration {
+	var total time.Duration
+	count := 0
+	for _, ep := range episodes {
+		if ep.Type != api.EpisodeType["DEFAULT"] {
+			continue
+		}
+		if count >= watched {
+			break
+		}
+		d, err := ep.GetDuration()
+		if err != nil || d <= 0 {
+			d = fallback
+		}
+		total += d
+		count++
+	}
+	// Episodes not in the first page
+	total += time.Duration(watched-count) * fallback
+	return total
+}
+
+func topTags(counts map[string]int, n int) []TagCount {
+	tags := make([]TagCount, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, TagCount{Name: name, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Name < tags[j].Name
+	})
+	return tags[:min(n, len(tags))]
+}
+
+func topRated(collections []api.UserSubjectCollection, n int) []api.UserSubjectCollection {
+	rated := make([]api.UserSubjectCollection, 0, len(collections))
+	for _, c := range collections {
+		if c.Rate > 0 {
+			rated = append(rated, c)
+		}
+	}
+	slices.SortStableFunc(rated, func(a, b api.UserSubjectCollection) int {
+		if a.Rate != b.Rate {
+			return int(b.Rate) - int(a.Rate)
+		}
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+	return rated[:min(n, len(rated))]
+}
+
+// Hours returns watch time in hours.
+func (s *Stats) Hours() float64 {
+	return s.WatchTime.Hours()
+}
+
+// RenderText renders the statistics with text bar charts.
+func RenderText(s *Stats) string {
+	var b strings.Builder
+	if s.Year > 0 {
+		fmt.Fprintf(&b, "Year %d\n", s.Year)
+	}
+	fmt.Fprintf(&b, "Total: %d\n\n", s.Total)
+
+	b.WriteString("Status\n")
+	statusBars := Bars(30, s.StatusCounts())[0]
+	for i, status := range api.C_STATUS {
+		fmt.Fprintf(&b, "%-9s %5d %s\n", status, s.ByStatus[status], statusBars[i])
+	}
+
+	b.WriteString("\nSubject type\n")
+	typeBars := Bars(30, s.TypeCounts())[0]
+	for i, t := range api.S_TYPE_ALL {
+		fmt.Fprintf(&b, "%-9s %5d %s\n", t, s.ByType[t], typeBars[i])
+	}
+
+	fmt.Fprintf(&b, "\nRating (mine %.2f / community %.2f, %d rated)\n", s.MeanRate, s.MeanScore, s.RatedCount)
+	b.WriteString(RenderRateComparison(s, 20))
+
+	b.WriteString("\nTop tags\n")
+	for _, tag := range s.TopTags {
+		fmt.Fprintf(&b, "%s(%d) ", tag.Name, tag.Count)
+	}
+	b.WriteString("\nTop community tags\n")
+	for _, tag := range s.TopSubjectTags {
+		fmt.Fprintf(&b, "%s(%d) ", tag.Name, tag.Count)
+	}
+	b.WriteString("\n\n")
+
+	fmt.Fprintf(&b, "Completion rate: %.1f%%\n", s.CompletionRate*100)
+	if s.Year > 0 {
+		fmt.Fprintf(&b, "Episodes watched of these subjects, all time: %d\n", s.Episodes)
+		fmt.Fprintf(&b, "Watch time of these subjects, all time: %.1f hours", s.Hours())
+	} else {
+		fmt.Fprintf(&b, "Episodes watched: %d (%.1f per week)\n", s.Episodes, s.EpisodesPerWeek)
+		fmt.Fprintf(&b, "Watch time: %.1f hours", s.Hours())
+	}
+	if s.Estimated > 0 {
+		fmt.Fprintf(&b, " (%d subjects estimated)", s.Estimated)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// RenderRateComparison renders our rate histogram beside the community score histogram.
+func RenderRateComparison(s *Stats, width int) string {
+	mine, community := s.RateBars(width)
+	var b strings.Builder
+	for i := 10; i >= 1; i-- {
+		fmt.Fprintf(&b, "%2d %4d %-*s %4d %s\n", i, s.RateHistogram[i], width, mine[i], s.ScoreHistogram[i], community[i])
+	}
+	return b.String()
+}
+
+// StatusCounts returns the count of each status in api.C_STATUS
+func (s *Stats) StatusCounts() []int {
+	counts := make([]int, len(api.C_STATUS))
+	for i, status := range api.C_STATUS {
+		counts[i] = s.ByStatus[status]
+	}
+	return counts
+}
+
+// TypeCounts returns the count of each subject type in api.S_TYPE_ALL
+func (s *Stats) TypeCounts() []int {
+	counts := make([]int, len(api.S_TYPE_ALL))
+	for i, t := range api.S_TYPE_ALL {
+		counts[i] = s.ByType[t]
+	}
+	return counts
+}
+
+// RateBars renders our rate and the community score histograms on one scale.
+// Index is the rate. Unrated subjects have no bar.
+func (s *Stats) RateBars(width int) (mine, community [11]string) {
+	bars := Bars(width, s.RateHistogram[1:], s.ScoreHistogram[1:])
+	copy(mine[1:], bars[0])
+	copy(community[1:], bars[1])
+	return mine, community
+}
+
+// RateMax is the largest count of the rate and score histograms, the scale of RateBars
+func (s *Stats) RateMax() int {
+	return HistogramMax(s.RateHistogram[1:], s.ScoreHistogram[1:])
+}
+
+// HistogramMax is the largest count in all series
+func HistogramMax(series ...[]int) int {
+	m := 0
+	for _, counts := range series {
+		for _, count := range counts {
+			m = max(m, count)
+		}
+	}
+	return m
+}
+
+// Bars renders each count as a bar of width. All series share the scale of the largest count.
+func Bars(width int, series ...[]int) [][]string {
+	maxCount := HistogramMax(series...)
+	bars := make([][]string, len(series))
+	for i, counts := range series {
+		bars[i] = make([]string, len(counts))
+		for j, count := range counts {
+			bars[i][j] = util.Bar(float64(count), float64(maxCount), width)
+		}
+	}
+	return bars
+}
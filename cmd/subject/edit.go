@@ -30,14 +30,14 @@ var editCmd = &cobra.Command{
 		if watch == -1 {
 			WatchNextEpisode(authClient, userInfo.Username, subjectId)
 		} else {
-			WatchToEpisode(authClient, subjectId, watch)
+			api.AbortOnError(WatchToEpisode(authClient, subjectId, watch))
 		}
 	},
 }
 
 func init() {
 	var watch int
-	editCmd.Flags().IntVarP(&watch, "watch", "w", -1, "Watch to episode [n]. -1 for next episode.")
+	editCmd.Flags().IntVarP(&watch, "watch", "w", -1, "Watch to main episode [n], specials are not counted. -1 for next episode.")
 	subCmd.AddCommand(editCmd)
 }
 
@@ -53,7 +53,7 @@ func WatchNextEpisode(c *api.AuthClient, username string, subjectId int) {
 		slog.Error(fmt.Sprintf("No more episodes to watch. Current: %d, Total: %d\n", epStatus, totalEps))
 	}
 
-	userEpisodeCollections, err := GetUserEpisodeCollections(c, subjectId, 0, 100, 0)
+	userEpisodeCollections, err := GetUserEpisodeCollections(c, subjectId, 0, 100, api.EpisodeType["DEFAULT"])
 	if err != nil {
 		slog.Error(fmt.Sprintf("%v\n", err))
 	}
@@ -71,11 +71,13 @@ func WatchNextEpisode(c *api.AuthClient, username string, subjectId int) {
 	slog.Info(fmt.Sprintf("Marked as done: %s episode %d. %s\n", subjectName, episode.Episode.ID, epName))
 }
 
-// Mark 1 to n episodes as done, the rest as delete
-func WatchToEpisode(c *api.AuthClient, subjectId int, episodeNum int) {
-	userEpisodeCollections, err := GetUserEpisodeCollections(c, subjectId, 0, 100, 0)
+// Mark main episodes 1 to n as done, the rest as delete.
+// SP, OP and ED are left as they are, so n is the same count as ep_status of the collection.
+func WatchToEpisode(c *api.AuthClient, subjectId int, episodeNum int) error {
+	userEpisodeCollections, err := GetUserEpisodeCollections(c, subjectId, 0, 100, api.EpisodeType["DEFAULT"])
 	if err != nil {
 		slog.Error(fmt.Sprintf("%v\n", err))
+		return err
 	}
 	totalEps := len(userEpisodeCollections.Data)
 	if episodeNum > totalEps {
@@ -92,16 +94,19 @@ func WatchToEpisode(c *api.AuthClient, subjectId int, episodeNum int) {
 		}
 	}
 
-	err = PatchEpisodes(c, subjectId, watchList, "done")
-	if err != nil {
-		slog.Error(fmt.Sprintf("Failed to mark episodes as done: %v\n", err))
-		return
+	if len(watchList) > 0 {
+		if err := PatchEpisodes(c, subjectId, watchList, "done"); err != nil {
+			slog.Error(fmt.Sprintf("Failed to mark episodes as done: %v\n", err))
+			return err
+		}
 	}
-	err = PatchEpisodes(c, subjectId, deleteList, "delete")
-	if err != nil {
-		slog.Error(fmt.Sprintf("Failed to delete episodes: %v\n", err))
-		return
+	if len(deleteList) > 0 {
+		if err := PatchEpisodes(c, subjectId, deleteList, "delete"); err != nil {
+			slog.Error(fmt.Sprintf("Failed to delete episodes: %v\n", err))
+			return err
+		}
 	}
+	return nil
 }
 
 // Return the first episode that is not done
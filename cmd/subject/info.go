@@ -1,12 +1,17 @@
 package subject
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"os"
 	"strconv"
+	"strings"
 
 	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/internal/cover"
+	"github.com/iucario/bangumi-go/util"
 	"github.com/spf13/cobra"
 )
 
@@ -25,16 +30,95 @@ var infoCmd = &cobra.Command{
 		subject, err := GetSubjectInfo(authClient, subjectId)
 		if err != nil {
 			fmt.Println("error", err)
-		} else {
-			fmt.Printf("%d\n%s\n%s\n%s\n", subject.ID, subject.NameCn, subject.Name, subject.Summary)
+			return
+		}
+		if showCover {
+			printCover(subject.Images)
 		}
+		fmt.Printf("%d\n%s\n%s\n%s\n", subject.ID, subject.NameCn, subject.Name, subject.Summary)
+
+		// Own rate is optional
+		var myRate uint32
+		if user := api.NewUser(authClient); user != nil {
+			if collection, err := GetUserSubjectCollection(authClient, user.Username, subjectId); err == nil {
+				myRate = collection.Rate
+			}
+		}
+		fmt.Println()
+		printRating(subject.Rating, myRate)
 	},
 }
 
+var (
+	showCover     bool
+	imageProtocol string
+)
+
 func init() {
+	infoCmd.Flags().BoolVar(&showCover, "cover", false, "show the cover image")
+	infoCmd.Flags().StringVar(&imageProtocol, "image-protocol", "auto", "auto, kitty, iterm, sixel or halfblock")
 	subCmd.AddCommand(infoCmd)
 }
 
+// printCover draws the cover above the text. Failures are logged and skipped.
+func printCover(images map[string]string) {
+	protocol, err := cover.ParseProtocol(imageProtocol)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	if protocol == cover.None {
+		return
+	}
+	img, err := cover.Load(context.Background(), cover.URL(images))
+	if err != nil {
+		slog.Error("failed to load cover", "error", err)
+		return
+	}
+	cols, rows := cover.Fit(img, 30, 20)
+	if err := cover.Encode(os.Stdout, img, cols, rows, protocol); err != nil {
+		slog.Error("failed to draw cover", "error", err)
+		return
+	}
+	if protocol == cover.Kitty {
+		// Kitty images are placed without moving the cursor
+		fmt.Print(strings.Repeat("\n", rows))
+	} else {
+		fmt.Println()
+	}
+}
+
+func printRating(r api.Rating, myRate uint32) {
+	fmt.Printf("Score: %.1f  Rank: %d  Votes: %d\n", r.Score, r.Rank, r.Total)
+	if r.Count.Total() > 0 {
+		fmt.Printf("Std dev: %.2f %s\n", r.StdDev(), r.Controversy())
+	}
+	for i, line := range RatingHistogram(r, 30) {
+		if uint32(10-i) == myRate {
+			line += " <- your rate"
+		}
+		fmt.Println(line)
+	}
+	if myRate == 0 {
+		fmt.Println("Your rate: none")
+	}
+}
+
+// RatingHistogram renders votes of scores 10 to 1 with text bars, one line per score.
+func RatingHistogram(r api.Rating, width int) []string {
+	votes := r.Count.Votes()
+	var maxVotes uint32
+	for _, n := range votes {
+		maxVotes = max(maxVotes, n)
+	}
+	lines := make([]string, 0, 10)
+	for score := 10; score >= 1; score-- {
+		bar := util.Bar(float64(votes[score]), float64(maxVotes), width)
+		lines = append(lines, fmt.Sprintf("%2d %-*s %d", score, width, bar, votes[score]))
+	}
+	return lines
+}
+
 func GetSubjectInfo(c api.Client, subjectId int) (*api.Subject, error) {
 	url := fmt.Sprintf("https://api.bgm.tv/v0/subjects/%d", subjectId)
 
@@ -49,3 +133,18 @@ func GetSubjectInfo(c api.Client, subjectId int) (*api.Subject, error) {
 	}
 	return &subject, nil
 }
+
+// GetRelatedSubjects returns sequels, prequels and other subjects related to a subject
+func GetRelatedSubjects(c api.Client, subjectId int) ([]api.RelatedSubject, error) {
+	url := fmt.Sprintf("https://api.bgm.tv/v0/subjects/%d/subjects", subjectId)
+
+	b, err := c.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	var related []api.RelatedSubject
+	if err := json.Unmarshal(b, &related); err != nil {
+		return nil, err
+	}
+	return related, nil
+}
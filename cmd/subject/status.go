@@ -120,7 +120,7 @@ func printSubjectStatus(c *api.AuthClient, subjectId int, collection api.UserSub
 	fmt.Printf("Your Tags: %s\n", tags)
 	fmt.Printf("Your Rating: %d\n", collection.Rate)
 
-	userEpisodes, _ := GetUserEpisodeCollections(c, subjectId, 0, 100, 0)
+	userEpisodes, _ := GetUserEpisodeCollections(c, subjectId, 0, 100, api.EpisodeType["DEFAULT"])
 	status := getEpisodeStatus(&userEpisodes.Data)
 	printEpisodeStatus(status)
 }
@@ -43,12 +43,14 @@ func GetUserSubjectCollection(c *api.AuthClient, username string, subjectId int)
 	return userSubjectCollection, err
 }
 
-// Get user's episode info
+// Get user's episode info of one episode type, e.g. api.EpisodeType["DEFAULT"] for main episodes
 func GetUserEpisodeCollections(c *api.AuthClient, subjectId, offset, limit, episode_type int) (api.UserEpisodeCollections, error) {
-	url := fmt.Sprintf("https://api.bgm.tv/v0/users/-/collections/%d/episodes", subjectId)
+	url := fmt.Sprintf("https://api.bgm.tv/v0/users/-/collections/%d/episodes?offset=%d&limit=%d&episode_type=%d",
+		subjectId, offset, limit, episode_type)
 	b, err := c.Get(url)
 	if err != nil {
 		slog.Error("getting user episode collection", "Error", err)
+		return api.UserEpisodeCollections{}, err
 	}
 	userEpisodeCollections := api.UserEpisodeCollections{}
 	err = json.Unmarshal(b, &userEpisodeCollections)
@@ -73,6 +75,21 @@ func GetEpisodes(c *api.HTTPClient, subjectID, offest, limit int) (*api.Episodes
 	return &episodes, err
 }
 
+// GetAllEpisodes fetches every page of the episodes of a subject
+func GetAllEpisodes(c *api.HTTPClient, subjectID int) ([]api.Episode, error) {
+	var episodes []api.Episode
+	for {
+		page, err := GetEpisodes(c, subjectID, len(episodes), 100)
+		if err != nil {
+			return episodes, err
+		}
+		episodes = append(episodes, page.Data...)
+		if len(page.Data) == 0 || len(episodes) >= page.Total {
+			return episodes, nil
+		}
+	}
+}
+
 // status: wish, done, watch, onhold, dropped
 // ep_status and vol_status are only used for book
 func PostCollection(c *api.AuthClient, subjectId int, status api.CollectionStatus, tags []string, comment string, rate int, private bool) error {
@@ -1,15 +1,22 @@
 package tui
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"slices"
-	"sync"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/iucario/bangumi-go/api"
 	"github.com/rivo/tview"
 
+	"github.com/iucario/bangumi-go/internal/config"
+	"github.com/iucario/bangumi-go/internal/cover"
+	"github.com/iucario/bangumi-go/internal/keymap"
+	"github.com/iucario/bangumi-go/internal/loader"
 	"github.com/iucario/bangumi-go/internal/ui"
+	"github.com/iucario/bangumi-go/util"
 )
 
 var PAGES = []string{
@@ -19,98 +26,240 @@ var PAGES = []string{
 	"stashed",
 	"dropped",
 	"calendar",
+	"season",
+	"backlog",
 	"help",
 	"subject",
 	"search",
+	"stats",
+	"user",
 }
 
 var MODALS = []string{
 	"alert",
+	"confirm",
 	"collect",
+	"username",
+	"palette",
 }
 
 // App controls the whole UI
 type App struct {
 	*tview.Application
-	Pages       *tview.Pages
-	User        *api.User
-	currentPage string
-	pageHistory []string // stack of page names for back navigation
-	statusBar   *ui.StatusBar
-}
-
-func NewApp(user *api.User) *App {
-	// Override the default styles
-	tview.Styles = ui.Styles
-	return &App{
-		Application: tview.NewApplication(),
+	Pages         *tview.Pages
+	pages         map[string]ui.Page // Pages by name, for looking up their data
+	User          *api.User
+	currentPage   string
+	history       []navState     // back navigation, last is the previous page
+	forward       []navState     // pages left by going back
+	subjects      []*SubjectPage // open subject pages, last is the latest
+	tabs          *TabBar
+	statusBar     *ui.StatusBar
+	profileBar    *tview.TextView // Profile and user at the right of the status bar
+	spinner       *ui.Spinner
+	loader        *loader.Loader
+	keymap        *keymap.Keymap
+	themes        map[string]ui.Theme // Custom themes from the config
+	imageProtocol cover.Protocol
+	location      *time.Location // Timezone of air times
+}
+
+// NewApp creates the app. theme overrides the theme in the config if not empty.
+func NewApp(user *api.User, theme string) *App {
+	cfg, cfgErr := config.Load()
+	themeFilesErr := cfg.LoadThemeFiles()
+	themeErr := errors.Join(themeFilesErr, applyStartTheme(theme, cfg))
+	application := tview.NewApplication()
+	spinner := ui.NewSpinner(application)
+	a := &App{
+		Application: application,
 		Pages:       tview.NewPages(),
+		pages:       make(map[string]ui.Page),
 		User:        user,
 		statusBar:   ui.NewStatusBar(),
+		profileBar:  tview.NewTextView().SetDynamicColors(true).SetTextAlign(tview.AlignRight).SetTextColor(ui.Styles.TertiaryTextColor),
+		spinner:     spinner,
+		loader:      loader.New(application, spinner),
+	}
+	a.loader.OnError = func(key string, err error) {
+		a.NotifyWithStyle(fmt.Sprintf("%s failed: %v", key, err), "error")
+	}
+	a.tabs = NewTabBar(a)
+	a.themes = cfg.Themes
+	a.EnableMouse(!cfg.NoMouse)
+	a.imageProtocol = cover.HalfBlock
+	a.location = time.Local
+	if cfgErr != nil {
+		slog.Error("failed to load config", "path", config.Path(), "error", cfgErr)
+		a.NotifyWithStyle(fmt.Sprintf("Invalid config %s: %v", config.Path(), cfgErr), "error")
+		a.keymap = keymap.Default()
+		return a
+	}
+	a.keymap = loadKeymap(a, cfg)
+	if protocol, err := cover.ParseProtocol(cfg.ImageProtocol); err != nil {
+		a.NotifyWithStyle(err.Error(), "error")
+	} else {
+		a.imageProtocol = protocol
 	}
+	if loc, err := cfg.Location(); err != nil {
+		a.NotifyWithStyle(err.Error(), "error")
+	} else {
+		a.location = loc
+	}
+	if themeErr != nil {
+		slog.Error("invalid theme", "error", themeErr)
+		a.NotifyWithStyle(fmt.Sprintf("Invalid theme: %v", themeErr), "error")
+	}
+	return a
 }
 
-// Run starts the TUI application with watching list and sets up the main pages.
-func (a *App) Run() error {
-	var wg sync.WaitGroup
-	wg.Add(len(PAGES) - 1) // Pages - subject
-
-	// Create all pages concurrently using sync.Map for thread safety
-	var collectionPages sync.Map
-	pageCreation := func(page ui.Page) {
-		defer wg.Done()
-		name := page.GetName()
-		a.Pages.AddPage(name, page, true, false)
-		if slices.Contains(api.C_STATUS, api.CollectionStatus(name)) {
-			if cp, ok := page.(*CollectionPage); ok {
-				collectionPages.Store(name, cp)
-			} else {
-				slog.Error("Failed to cast page to CollectionPage", "Name", name)
-			}
+// applyStartTheme applies the theme of the flag, else of the config, else a fallback
+// for limited terminals. The default theme is kept if the theme is invalid.
+func applyStartTheme(theme string, cfg *config.Config) error {
+	if theme == "" {
+		theme = cfg.Theme
+	}
+	if theme == "" {
+		theme = ui.FallbackTheme()
+	}
+	if theme == "" {
+		return nil
+	}
+	return ui.ApplyTheme(theme, cfg.Themes)
+}
+
+// loadKeymap creates the keymap of the config. The vim preset is used if the keymap is invalid.
+func loadKeymap(a *App, cfg *config.Config) *keymap.Keymap {
+	km, err := keymap.New(cfg.Keymap.Preset, cfg.Keymap.Bindings)
+	if err != nil {
+		slog.Error("invalid keymap", "error", err)
+		a.NotifyWithStyle(fmt.Sprintf("Invalid keymap: %v", err), "error")
+		return keymap.Default()
+	}
+	if conflicts := km.Conflicts(); len(conflicts) > 0 {
+		for _, c := range conflicts {
+			slog.Warn("key conflict", "conflict", c.String())
 		}
+		a.NotifyWithStyle(fmt.Sprintf("Key conflict: %s", conflicts[0]), "warning")
 	}
+	return km
+}
 
-	// Create collection + other pages concurrently
-	for _, status := range api.C_STATUS {
-		go func(status api.CollectionStatus) {
-			page := NewCollectionPage(a, status)
-			pageCreation(page)
-		}(status)
-	}
-	go func() {
-		pageCreation(NewCalendarPage(a))
-	}()
-	go func() {
-		pageCreation(NewHelpPage(a))
-	}()
-	go func() {
-		pageCreation(NewSearchPage(a))
-	}()
-
-	// Wait for all pages to be created
-	wg.Wait()
-
-	// Switch to the initial page
-	a.Goto("calendar")
-	a.PushPage("calendar")
+// Run starts the TUI application with watching list and sets up the main pages.
+// Pages load their data in the background so they are created without waiting.
+func (a *App) Run() error {
+	a.addMainPages()
+
+	// Open the page of the last run
+	a.restoreLastSession()
 
 	// Start the application
 	container := tview.NewGrid()
-	container.SetRows(0, 1)
+	profile := a.profileLabel()
+	container.SetRows(1, 0, 1)
+	container.SetColumns(0, tview.TaggedStringWidth(profile)+1, 2)
 	container.SetBorder(false)
 	container.SetBorders(false)
-	container.AddItem(a.Pages, 0, 0, 1, 1, 0, 0, true)
-	container.AddItem(a.statusBar, 1, 0, 1, 1, 0, 0, false)
+	container.AddItem(a.tabs, 0, 0, 1, 3, 0, 0, false)
+	container.AddItem(a.Pages, 1, 0, 1, 3, 0, 0, true)
+	container.AddItem(a.statusBar, 2, 0, 1, 1, 0, 0, false)
+	container.AddItem(a.profileBar.SetText(profile), 2, 1, 1, 1, 0, 0, false)
+	container.AddItem(a.spinner, 2, 2, 1, 1, 0, 0, false)
 
 	// Set up global input capture to clear status bar on user interaction
 	a.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if a.statusBar != nil {
 			a.statusBar.Clear()
 		}
+		// Special keys open the palette from anywhere. Runes are left to inputs.
+		special := event.Key() != tcell.KeyRune || event.Modifiers()&tcell.ModAlt != 0
+		if special && !a.Pages.HasPage("palette") && a.keymap.Match(keymap.Global, event) == keymap.Palette {
+			a.OpenPalette()
+			return nil
+		}
 		return event
 	})
 
-	return a.Application.SetRoot(container, true).SetFocus(a.Pages).Run()
+	a.SetAfterDrawFunc(a.drawGraphics)
+
+	err := a.Application.SetRoot(container, true).SetFocus(a.Pages).Run()
+	a.saveSession()
+	return err
+}
+
+// drawGraphics writes the cover of the visible subject to the terminal after the screen is shown.
+// tcell can not draw terminal graphics itself. Covers of hidden subjects are removed first.
+func (a *App) drawGraphics(screen tcell.Screen) {
+	visible := a.visibleSubject()
+	var out []byte
+	for _, page := range a.subjects {
+		if page != visible {
+			out = append(out, page.coverView.AfterDraw(screen)...)
+		}
+	}
+	if visible != nil {
+		out = append(out, visible.coverView.AfterDraw(screen)...)
+	}
+	if len(out) == 0 {
+		return
+	}
+	a.QueueUpdate(func() {
+		if tty, ok := screen.Tty(); ok {
+			if _, err := tty.Write(out); err != nil {
+				slog.Error("failed to draw cover", "error", err)
+			}
+		}
+	})
+}
+
+// addMainPages creates the pages that are always present
+func (a *App) addMainPages() {
+	for _, status := range api.C_STATUS {
+		a.addPage(NewCollectionPage(a, status))
+	}
+	a.addPage(NewCalendarPage(a))
+	a.addPage(NewSeasonPage(a))
+	a.addPage(NewBacklogPage(a))
+	a.addPage(NewHelpPage(a))
+	a.addPage(NewSearchPage(a))
+	a.addPage(NewStatsPage(a))
+}
+
+// SetTheme switches the theme. Pages are created again with the new colors
+// and reload their data. The selection of the current page is kept.
+func (a *App) SetTheme(name string) error {
+	if err := ui.ApplyTheme(name, a.themes); err != nil {
+		return err
+	}
+	a.statusBar.SetBackgroundColor(ui.Styles.PrimitiveBackgroundColor)
+	a.spinner.SetBackgroundColor(ui.Styles.PrimitiveBackgroundColor)
+	a.profileBar.SetBackgroundColor(ui.Styles.PrimitiveBackgroundColor)
+	a.profileBar.SetTextColor(ui.Styles.TertiaryTextColor)
+	a.spinner.SetTextColor(ui.Styles.TertiaryTextColor)
+	a.tabs.SetBackgroundColor(ui.Styles.PrimitiveBackgroundColor)
+	a.tabs.SetTextColor(ui.Styles.PrimaryTextColor)
+
+	for _, name := range MODALS {
+		a.Pages.RemovePage(name)
+	}
+	state := a.stateOf(a.currentPage)
+	a.addMainPages()
+	a.subjects = nil
+	if page, ok := a.pages["subject"].(*SubjectPage); ok {
+		a.addPage(a.subjectPage(page.ID))
+	}
+	if page, ok := a.pages["user"].(*CollectionPage); ok {
+		a.addPage(NewUserCollectionPage(a, page.Username, page.CollectionStatus))
+	}
+	a.restore(state)
+	a.SetFocus(a.Pages)
+	return nil
+}
+
+// addPage adds or replaces a page
+func (a *App) addPage(page ui.Page) {
+	a.pages[page.GetName()] = page
+	a.Pages.AddPage(page.GetName(), page, true, false)
 }
 
 // GoHome switchs app to page "watching"
@@ -125,46 +274,40 @@ func (a *App) Goto(page string) {
 		return
 	}
 	// Should push subject page to history if switching from there
-	if a.currentPage == "subject" {
+	if a.currentPage == "subject" && page != "subject" {
 		a.PushPage(a.currentPage)
 	}
-	a.Pages.SwitchToPage(page)
-	a.currentPage = page
+	a.show(page)
 }
 
-// PushPage adds a page to the history stack
-func (a *App) PushPage(name string) {
-	a.pageHistory = append(a.pageHistory, name)
+// show switches to a page without changing the history
+func (a *App) show(page string) {
+	a.Pages.SwitchToPage(page)
+	a.currentPage = page
+	a.tabs.Update()
 }
 
-// PopPage removes and returns the last page from the history stack
-func (a *App) PopPage() (string, bool) {
-	if len(a.pageHistory) == 0 {
-		return "", false
-	}
-	index := len(a.pageHistory) - 1
-	name := a.pageHistory[index]
-	a.pageHistory = slices.Delete(a.pageHistory, index, index+1)
-	return name, true
+// OpenSubjectPage pushes the current page to history and opens a subject page.
+// Subjects opened before are shown as they were left.
+func (a *App) OpenSubjectPage(subjectID int, prevPage string) {
+	a.PushPage(prevPage)
+	a.addPage(a.subjectPage(subjectID))
+	a.Goto("subject")
 }
 
-// Go back if there is a history, else do nothing
-func (a *App) GoBack() {
-	if prev, ok := a.PopPage(); ok {
-		a.Goto(prev)
-	}
+// OpenUserPage pushes the current page to history and opens the read-only collection of a user
+func (a *App) OpenUserPage(username string, prevPage string) {
+	page := NewUserCollectionPage(a, username, api.Done)
+	a.PushPage(prevPage)
+	a.addPage(page)
+	a.Goto("user")
 }
 
-// OpenSubjectPage pushes the current page to history and opens a subject page
-func (a *App) OpenSubjectPage(subjectID int, prevPage string) {
-	a.PushPage(prevPage)
-	page := NewSubjectPage(a, subjectID)
-	if page == nil {
-		slog.Error("opening subject page", "ID", subjectID)
-		return
-	}
-	a.Pages.AddPage("subject", page, true, false)
-	a.Goto("subject")
+// OpenUserModal asks for a username to open the user's collection
+func (a *App) OpenUserModal() {
+	modal := NewUserModal(a)
+	a.Pages.AddPage("username", modal, true, true)
+	a.SetFocus(modal)
 }
 
 func (a *App) OpenHelpPage() {
@@ -172,6 +315,15 @@ func (a *App) OpenHelpPage() {
 	a.Goto("help")
 }
 
+// profileLabel is the active profile and the user, e.g. "club · alice"
+func (a *App) profileLabel() string {
+	label := tview.Escape(util.Profile())
+	if a.User != nil && a.User.Username != "" {
+		label += " · " + tview.Escape(a.User.Username)
+	}
+	return label
+}
+
 // Notify shows a notification message in the status bar.
 func (a *App) Notify(message string) {
 	if a.statusBar != nil {
@@ -199,27 +351,76 @@ func (a *App) Alert(message string) {
 	a.Pages.AddPage("alert", modal, true, true)
 }
 
-func (a *App) handlePageSwitch(key rune) {
-	switch key {
-	case '1':
+// Confirm asks before an action and runs onYes if confirmed
+func (a *App) Confirm(message string, onYes func()) {
+	modal := tview.NewModal().
+		SetText(message).
+		AddButtons([]string{"Yes", "No"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			a.Pages.RemovePage("confirm")
+			a.SetFocus(a.Pages)
+			if buttonLabel == "Yes" {
+				onYes()
+			}
+		})
+	modal.SetTitle("Confirm").SetTitleColor(ui.Styles.GraphicsColor)
+	a.Pages.AddPage("confirm", modal, true, true)
+}
+
+// handleGlobalKey runs the global action bound to the key. It reports whether the key is handled.
+func (a *App) handleGlobalKey(event *tcell.EventKey) bool {
+	switch a.keymap.Match(keymap.Global, event) {
+	case keymap.GotoWatching:
 		a.Goto("watching")
-	case '2':
+	case keymap.GotoWish:
 		a.Goto("wish")
-	case '3':
+	case keymap.GotoDone:
 		a.Goto("done")
-	case '4':
+	case keymap.GotoStashed:
 		a.Goto("stashed")
-	case '5':
+	case keymap.GotoDropped:
 		a.Goto("dropped")
-	case '6':
+	case keymap.GotoCalendar:
 		a.Goto("calendar")
-	case '7':
+	case keymap.GotoSearch:
 		a.Goto("search")
-	case 'Q':
+	case keymap.GotoStats:
+		a.Goto("stats")
+	case keymap.GotoSeason:
+		a.Goto("season")
+	case keymap.GotoBacklog:
+		a.Goto("backlog")
+	case keymap.OpenUser:
+		a.OpenUserModal()
+	case keymap.Palette:
+		a.OpenPalette()
+	case keymap.Quit:
 		a.Stop()
-	case 'q', rune(tcell.KeyEsc):
+	case keymap.Back:
 		a.GoBack()
-	case '?':
+	case keymap.Forward:
+		a.GoForward()
+	case keymap.Recent:
+		a.OpenRecent()
+	case keymap.Help:
 		a.OpenHelpPage()
+	default:
+		return false
+	}
+	return true
+}
+
+// handleScrollKeys captures input events for the primitive and moves it with the scroll keys.
+func (a *App) handleScrollKeys(p tview.Primitive) func(event *tcell.EventKey) *tcell.EventKey {
+	return func(event *tcell.EventKey) *tcell.EventKey {
+		switch a.keymap.Match(keymap.Scroll, event) {
+		case keymap.ScrollDown:
+			p.InputHandler()(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone), nil)
+			return nil
+		case keymap.ScrollUp:
+			p.InputHandler()(tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone), nil)
+			return nil
+		}
+		return event
 	}
 }
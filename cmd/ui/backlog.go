@@ -0,0 +1,314 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd/backlog"
+	"github.com/iucario/bangumi-go/cmd/calendar"
+	"github.com/iucario/bangumi-go/cmd/subject"
+	"github.com/iucario/bangumi-go/internal/keymap"
+	"github.com/iucario/bangumi-go/internal/loader"
+	"github.com/iucario/bangumi-go/internal/ui"
+	"github.com/rivo/tview"
+)
+
+// BacklogPage lists watching anime that are behind or stale, with bulk actions on marked rows
+type BacklogPage struct {
+	*tview.Grid
+	app    *App
+	items  []backlog.Item // nil until loaded
+	marked map[int]bool   // Marked subject IDs
+	header *tview.TextView
+	table  *tview.Table
+}
+
+// NewBacklogPage creates the page. It is loaded when the page is first shown.
+func NewBacklogPage(a *App) *BacklogPage {
+	p := &BacklogPage{
+		Grid:   tview.NewGrid(),
+		app:    a,
+		marked: make(map[int]bool),
+		header: tview.NewTextView().SetTextAlign(tview.AlignCenter),
+		table:  ui.NewTable().SetSelectable(true, false).SetFixed(1, 0),
+	}
+	p.header.SetTextColor(ui.Styles.TitleColor)
+	footer := tview.NewTextView().SetDynamicColors(true).SetTextAlign(tview.AlignCenter).
+		SetText("m: 标记  a: 标记过期  s: 搁置  d: 抛弃  c: 补完已播  R: 刷新  ?: Help")
+	p.SetRows(1, 0, 1).SetColumns(0)
+	p.AddItem(p.header, 0, 0, 1, 1, 0, 0, false).
+		AddItem(p.table, 1, 0, 1, 1, 0, 0, true).
+		AddItem(footer, 2, 0, 1, 1, 0, 0, false)
+	p.render()
+	p.setKeyBindings()
+	return p
+}
+
+func (p *BacklogPage) GetName() string {
+	return "backlog"
+}
+
+// Focus loads the backlog the first time the page is shown
+func (p *BacklogPage) Focus(delegate func(tview.Primitive)) {
+	if p.items == nil && !p.app.loader.Loading("backlog") {
+		p.Refresh()
+	}
+	delegate(p.table)
+}
+
+// Refresh fetches the episodes of the watching list in the background
+func (p *BacklogPage) Refresh() {
+	username := p.app.User.Username
+	loader.Load(p.app.loader, "backlog", func(ctx context.Context) ([]backlog.Item, error) {
+		watched, _, err := calendar.FetchWatched(p.app.User.Client.WithContext(ctx), username)
+		if err != nil {
+			return nil, err
+		}
+		return backlog.Build(watched, time.Now(), backlog.StaleAfter(backlog.DefaultStaleDays)), nil
+	}, func(items []backlog.Item, err error) {
+		if err == nil {
+			if items == nil {
+				items = []backlog.Item{}
+			}
+			p.items = items
+			// Keep marks of subjects still listed
+			for id := range p.marked {
+				if p.index(id) < 0 {
+					delete(p.marked, id)
+				}
+			}
+		}
+		p.render()
+	})
+	p.render()
+}
+
+func (p *BacklogPage) render() {
+	var behind int
+	var remaining time.Duration
+	for _, item := range p.items {
+		behind += item.Behind
+		remaining += item.Remaining()
+	}
+	title := "补番"
+	if p.app.loader.Loading("backlog") {
+		title += " (loading...)"
+	} else if p.items != nil {
+		title = fmt.Sprintf("补番 · %d 集 · %s · 已标记 %d", behind, backlog.FormatDuration(remaining), len(p.marked))
+	}
+	p.header.SetText(title)
+
+	row, _ := p.table.GetSelection()
+	p.table.Clear()
+	for col, name := range []string{"", "Behind", "Progress", "Remaining", "Updated", "Title"} {
+		p.table.SetCell(0, col, tview.NewTableCell(name).SetTextColor(ui.YellowColor()).SetSelectable(false))
+	}
+	for i, item := range p.items {
+		mark := " "
+		if p.marked[item.SubjectID] {
+			mark = "*"
+		}
+		remaining := backlog.FormatDuration(item.Remaining())
+		if item.Estimated > 0 {
+			remaining = "~" + remaining
+		}
+		updated := tview.NewTableCell(item.UpdatedAt.Format("2006-01-02"))
+		if item.Stale {
+			updated.SetTextColor(ui.RedColor())
+		} else {
+			updated.SetTextColor(ui.GreyColor())
+		}
+		p.table.SetCell(i+1, 0, tview.NewTableCell(mark).SetTextColor(ui.CyanColor()).SetReference(item.SubjectID))
+		p.table.SetCell(i+1, 1, tview.NewTableCell(fmt.Sprintf("%d", item.Behind)).SetAlign(tview.AlignRight))
+		p.table.SetCell(i+1, 2, tview.NewTableCell(fmt.Sprintf("%d/%d", item.Watched, item.Aired)).SetAlign(tview.AlignRight))
+		p.table.SetCell(i+1, 3, tview.NewTableCell(remaining).SetAlign(tview.AlignRight))
+		p.table.SetCell(i+1, 4, updated)
+		p.table.SetCell(i+1, 5, tview.NewTableCell(tview.Escape(item.Name)).SetExpansion(1))
+	}
+	p.table.Select(max(1, min(row, len(p.items))), 0)
+}
+
+// index returns the index of a subject in the items, -1 if not listed
+func (p *BacklogPage) index(id int) int {
+	for i, item := range p.items {
+		if item.SubjectID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// selectedID returns the subject ID of the selected row, 0 if none
+func (p *BacklogPage) selectedID() int {
+	row, _ := p.table.GetSelection()
+	id, _ := p.table.GetCell(row, 0).GetReference().(int)
+	return id
+}
+
+// targets are the marked items, or the selected item if none is marked
+func (p *BacklogPage) targets() []backlog.Item {
+	var items []backlog.Item
+	for _, item := range p.items {
+		if p.marked[item.SubjectID] {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		if i := p.index(p.selectedID()); i >= 0 {
+			items = append(items, p.items[i])
+		}
+	}
+	return items
+}
+
+func (p *BacklogPage) toggleMark() {
+	id := p.selectedID()
+	if id == 0 {
+		return
+	}
+	if p.marked[id] {
+		delete(p.marked, id)
+	} else {
+		p.marked[id] = true
+	}
+	p.render()
+	if row, _ := p.table.GetSelection(); row < len(p.items) {
+		p.table.Select(row+1, 0)
+	}
+}
+
+func (p *BacklogPage) markStale() {
+	for _, item := range p.items {
+		if item.Stale {
+			p.marked[item.SubjectID] = true
+		}
+	}
+	p.render()
+}
+
+// bulk asks for confirmation and applies the action to the targets in the background.
+// The backlog and the affected collection pages are refreshed afterwards.
+func (p *BacklogPage) bulk(verb string, action func(ctx context.Context, item backlog.Item) error, pages ...api.CollectionStatus) {
+	items := p.targets()
+	if len(items) == 0 {
+		return
+	}
+	message := fmt.Sprintf("%s %s?", verb, items[0].Name)
+	if len(items) > 1 {
+		message = fmt.Sprintf("%s %d subjects?", verb, len(items))
+	}
+	// Keyed by the subjects, so only the same action on the same subjects cancels it
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = strconv.Itoa(item.SubjectID)
+	}
+	key := fmt.Sprintf("%s %s", strings.ToLower(verb), strings.Join(ids, ","))
+	p.app.Confirm(message, func() {
+		loader.Load(p.app.loader, key, func(ctx context.Context) (struct{}, error) {
+			var errs []error
+			for _, item := range items {
+				if err := action(ctx, item); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", item.Name, err))
+				}
+			}
+			return struct{}{}, errors.Join(errs...)
+		}, func(_ struct{}, err error) {
+			if err == nil {
+				p.app.NotifyWithStyle(fmt.Sprintf("%s %d subjects", verb, len(items)), "success")
+			}
+			for _, item := range items {
+				delete(p.marked, item.SubjectID)
+			}
+			for _, status := range pages {
+				if page, ok := p.app.pages[status.String()].(*CollectionPage); ok {
+					page.Refresh()
+				}
+			}
+			p.Refresh()
+		})
+	})
+}
+
+// moveTo changes the status of the targets and keeps their tags, rate and comment
+func (p *BacklogPage) moveTo(status api.CollectionStatus, verb string) {
+	p.bulk(verb, func(ctx context.Context, item backlog.Item) error {
+		original := item.Collection
+		updated := item.Collection
+		updated.SetStatus(status)
+		return subject.PatchCollection(p.app.User.Client.WithContext(ctx), &original, &updated)
+	}, api.Watching, status)
+}
+
+// catchUp marks the aired episodes of the targets watched
+func (p *BacklogPage) catchUp() {
+	p.bulk("Catch up", func(ctx context.Context, item backlog.Item) error {
+		return subject.WatchToEpisode(p.app.User.Client.WithContext(ctx), item.SubjectID, item.Aired)
+	}, api.Watching)
+}
+
+func (p *BacklogPage) openSelected() {
+	if id := p.selectedID(); id != 0 {
+		p.app.OpenSubjectPage(id, "backlog")
+	}
+}
+
+func (p *BacklogPage) setKeyBindings() {
+	p.table.SetSelectedFunc(func(row, column int) {
+		p.openSelected()
+	})
+	doubleClickToOpen(p.table, p.openSelected)
+	p.table.SetInputCapture(p.app.handleScrollKeys(p.table))
+	p.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch p.app.keymap.Match(keymap.Backlog, event) {
+		case keymap.Open:
+			p.openSelected()
+		case keymap.BacklogMark:
+			p.toggleMark()
+		case keymap.BacklogMarkStale:
+			p.markStale()
+		case keymap.BacklogStash:
+			p.moveTo(api.OnHold, "Move to on-hold")
+		case keymap.BacklogDrop:
+			p.moveTo(api.Dropped, "Drop")
+		case keymap.BacklogCatchUp:
+			p.catchUp()
+		case keymap.Refresh:
+			p.Refresh()
+		default:
+			if p.app.handleGlobalKey(event) {
+				return nil
+			}
+			return event
+		}
+		return nil
+	})
+}
+
+func (p *BacklogPage) navState() navState {
+	row, _ := p.table.GetSelection()
+	offset, _ := p.table.GetOffset()
+	return navState{SubjectID: p.selectedID(), Row: row, Offset: offset}
+}
+
+func (p *BacklogPage) restoreNav(state navState) {
+	if i := p.index(state.SubjectID); i >= 0 {
+		p.table.Select(i+1, 0)
+	} else if state.Row > 0 && state.Row <= len(p.items) {
+		p.table.Select(state.Row, 0)
+	}
+	p.table.SetOffset(state.Offset, 0)
+}
+
+func (p *BacklogPage) paletteSubjects() []paletteSubject {
+	subjects := make([]paletteSubject, len(p.items))
+	for i, item := range p.items {
+		subjects[i] = paletteSubject{ID: item.SubjectID, Name: item.Collection.Subject.Name, NameCn: item.Collection.Subject.NameCn}
+	}
+	return subjects
+}
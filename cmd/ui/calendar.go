@@ -1,13 +1,20 @@
 package tui
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"sort"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/iucario/bangumi-go/api"
 	"github.com/iucario/bangumi-go/cmd/calendar"
+	"github.com/iucario/bangumi-go/internal/config"
+	"github.com/iucario/bangumi-go/internal/keymap"
+	"github.com/iucario/bangumi-go/internal/loader"
 	"github.com/iucario/bangumi-go/internal/ui"
 	"github.com/rivo/tview"
 )
@@ -18,17 +25,40 @@ type CalendarPage struct {
 	app    *App
 	data   []api.Calendar
 	table  *tview.Table
+	// Selection to restore when the calendar is loaded
+	pending *navState
+	view    calendarView
+	// Items in the watching and wish lists by subject ID. nil until loaded.
+	mine map[int]calendar.MineItem
+	// Broadcast slots by subject ID, of the days selected in the timetable and my lists
+	broadcasts map[int]api.Broadcast
+	// Subjects whose slots are loaded or loading
+	scheduled map[int]bool
+}
+
+// calendarView is the persisted mode of the calendar
+type calendarView struct {
+	Mine bool `json:"mine"` // Highlight subjects in my lists with progress
+	Hide bool `json:"hide"` // Hide subjects not in my lists
+	// Rows of hours with air times in the timezone of the config
+	Timetable bool `json:"timetable"`
 }
 
 func NewCalendarPage(app *App) *CalendarPage {
 	calendar := &CalendarPage{
-		Grid:   tview.NewGrid(),
-		client: api.NewHTTPClient(""),
-		app:    app,
+		Grid:       tview.NewGrid(),
+		client:     api.NewHTTPClient(""),
+		app:        app,
+		table:      ui.NewTable(),
+		broadcasts: make(map[int]api.Broadcast),
+		scheduled:  make(map[int]bool),
+	}
+	if err := config.LoadState("calendar", &calendar.view); err != nil {
+		slog.Error("Failed to load calendar view", "error", err)
 	}
-	calendar.fetchData()
 	calendar.render()
 	calendar.setKeyBindings()
+	calendar.fetchData()
 	return calendar
 }
 
@@ -36,49 +66,226 @@ func (c *CalendarPage) GetName() string {
 	return "calendar"
 }
 
+// fetchData loads the calendar in the background and renders it when done
 func (c *CalendarPage) fetchData() {
-	calendars, err := calendar.GetCalendar(c.client)
-	if err != nil {
-		slog.Error("Failed to fetch calendar data", "error", err)
+	loader.Load(c.app.loader, "calendar", func(ctx context.Context) ([]api.Calendar, error) {
+		return calendar.GetCalendar(c.client.WithContext(ctx))
+	}, func(calendars []api.Calendar, err error) {
+		if err != nil {
+			return
+		}
+		c.data = calendars
+		c.rerender()
+		if c.view.Mine || c.view.Hide {
+			c.fetchMine()
+		}
+		c.fetchSchedule()
+	})
+}
+
+// fetchSchedule loads the broadcast slots of the subjects on the selected day and in my lists.
+// Each subject needs a request the first time, so other days load when they are selected.
+func (c *CalendarPage) fetchSchedule() {
+	if c.data == nil || !c.view.Timetable {
+		return
+	}
+	_, column := c.table.GetSelection()
+	day := time.Now().In(c.app.location).AddDate(0, 0, max(column-1, 0))
+	weekday := uint32(day.Weekday()+6)%7 + 1 // Calendar weekdays start with 1 on Monday
+	var ids []int
+	for _, cal := range c.data {
+		for _, item := range cal.Items {
+			if _, mine := c.mine[item.ID]; (cal.Weekday.ID == weekday || mine) && !c.scheduled[item.ID] {
+				ids = append(ids, item.ID)
+				c.scheduled[item.ID] = true
+			}
+		}
+	}
+	if len(ids) == 0 {
 		return
 	}
-	c.data = calendars
+	// Loads have different subjects, so the first one keys the load and none cancels another
+	key := fmt.Sprintf("calendar.schedule %d", ids[0])
+	loader.Load(c.app.loader, key, func(ctx context.Context) (map[int]api.Broadcast, error) {
+		return calendar.GetBroadcasts(c.client.WithContext(ctx), ids), nil
+	}, func(broadcasts map[int]api.Broadcast, err error) {
+		maps.Copy(c.broadcasts, broadcasts)
+		c.rerender()
+	})
+}
+
+// fetchMine loads the watching and wish lists and the aired episodes of their airing subjects
+func (c *CalendarPage) fetchMine() {
+	if c.data == nil || c.app.User.Username == "" {
+		return
+	}
+	calendars := c.data
+	loader.Load(c.app.loader, "calendar.mine", func(ctx context.Context) ([]calendar.MineItem, error) {
+		client := c.app.User.Client.WithContext(ctx)
+		collections, err := calendar.GetMineCollections(client, c.app.User.Username)
+		if err != nil {
+			return nil, err
+		}
+		items := calendar.Mine(calendars, collections)
+		calendar.FetchLatest(client.HTTPClient, items)
+		return items, nil
+	}, func(items []calendar.MineItem, err error) {
+		if err != nil {
+			return
+		}
+		c.mine = make(map[int]calendar.MineItem, len(items))
+		for _, item := range items {
+			c.mine[item.ID] = item
+		}
+		c.rerender()
+		c.fetchSchedule()
+	})
+}
+
+// rerender renders the calendar again and keeps the selected subject
+func (c *CalendarPage) rerender() {
+	state := c.navState()
+	if c.pending != nil {
+		state = *c.pending
+		c.pending = nil
+	}
+	c.render()
+	c.restoreNav(state)
+}
+
+// toggleView switches a mode and loads my lists or air times if needed
+func (c *CalendarPage) toggleView(action keymap.Action) {
+	switch action {
+	case keymap.CalendarMine:
+		c.view.Mine = !c.view.Mine
+	case keymap.CalendarHide:
+		c.view.Hide = !c.view.Hide
+	case keymap.Timetable:
+		c.view.Timetable = !c.view.Timetable
+	}
+	if err := config.SaveState("calendar", c.view); err != nil {
+		slog.Error("Failed to save calendar view", "error", err)
+	}
+	if (c.view.Mine || c.view.Hide) && c.mine == nil {
+		c.fetchMine()
+	}
+	c.rerender()
+	c.fetchSchedule()
+}
+
+// items returns the items of a weekday to show, mine first if highlighted, then by followers
+func (c *CalendarPage) items(cal api.Calendar) []api.CalendarItem {
+	items := make([]api.CalendarItem, 0, len(cal.Items))
+	for _, item := range cal.Items {
+		if _, ok := c.mine[item.ID]; ok || !c.view.Hide || c.mine == nil {
+			items = append(items, item)
+		}
+	}
+	followers := func(item api.CalendarItem) uint32 {
+		return item.CollectionCount.Wish + item.CollectionCount.Watching + item.CollectionCount.Done
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if c.view.Mine {
+			_, mineI := c.mine[items[i].ID]
+			_, mineJ := c.mine[items[j].ID]
+			if mineI != mineJ {
+				return mineI
+			}
+		}
+		return followers(items[i]) > followers(items[j])
+	})
+	return items
+}
+
+// cell shows the name of an item after prefix. In the mine mode, subjects in my lists are colored
+// by status with the progress, which is red if aired episodes are not watched.
+func (c *CalendarPage) cell(anime api.CalendarItem, prefix string) *tview.TableCell {
+	name := anime.GetName()
+	// Limit name width
+	maxWidth := 10
+	runeName := []rune(name)
+	if len(runeName) > maxWidth {
+		name = string(runeName[:maxWidth-1]) + "…"
+	}
+	name = prefix + name
+	cell := tview.NewTableCell(tview.Escape(name)).
+		SetReference(anime.ID).
+		SetTextColor(ui.Styles.PrimaryTextColor).
+		SetAlign(tview.AlignLeft)
+	mine, ok := c.mine[anime.ID]
+	if !c.view.Mine || !ok {
+		return cell
+	}
+	progress := ui.Grey(mine.Progress())
+	if mine.Behind() {
+		progress = ui.Red(mine.Progress())
+	}
+	cell.SetText(fmt.Sprintf("%s %s", tview.Escape(name), progress))
+	if mine.Collection.GetStatus() == api.Watching {
+		return cell.SetTextColor(ui.GreenColor()).SetAttributes(tcell.AttrBold)
+	}
+	return cell.SetTextColor(ui.CyanColor())
+}
+
+// calendarEntry is an item placed on the weekday it airs in the timezone of air times
+type calendarEntry struct {
+	item    api.CalendarItem
+	column  int       // Days after today
+	start   time.Time // Next broadcast in the timezone, zero if the time is unknown
+	hasTime bool
+}
+
+// entries places the items to show on the days they air. Items without a broadcast slot
+// use the weekday of the calendar.
+func (c *CalendarPage) entries(now time.Time) []calendarEntry {
+	var entries []calendarEntry
+	for _, cal := range c.data {
+		for _, item := range c.items(cal) {
+			weekday := calendar.AirWeekday(item.ID, cal.Weekday.ID, c.broadcasts, now, now.Location())
+			entry := calendarEntry{item: item, column: (int(weekday) - int(now.Weekday()) + 7) % 7}
+			if b, ok := c.broadcasts[item.ID]; ok && b.HasTime() {
+				entry.start = b.Next(now).In(now.Location())
+				entry.hasTime = true
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
 }
 
 func (c *CalendarPage) render() {
 	c.SetRows(1, -1, 1)
 	c.SetColumns(-1) // One full-width column
+	now := time.Now().In(c.app.location)
+	title := "放送日历"
+	if c.view.Hide {
+		title += " · 只看我的"
+	} else if c.view.Mine {
+		title += " · 我的"
+	}
+	if c.view.Timetable {
+		title += fmt.Sprintf(" · 时间表 (%s)", now.Format("MST"))
+	}
 	header := tview.NewTextView().
-		SetText("放送日历").
+		SetText(title).
 		SetTextAlign(tview.AlignCenter).
 		SetTextColor(ui.Styles.TitleColor)
-	c.table = tview.NewTable().SetSelectable(true, true)
+	c.table.Clear()
+	c.table.SetSelectable(true, true)
 	c.table.SetFixed(1, 0)
 	c.table.SetBorder(false)
 	c.table.Select(1, 0)
 
-	// Map weekday ID to column (0=Monday, 6=Sunday)
-	weekdayNames := []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
-	today := int(time.Now().Weekday())
-	if today == 0 {
-		today = 6 // Sunday
-	} else {
-		today-- // Monday=0
-	}
-
-	// Rotate weekdayNames so that today is first
-	rotatedWeekdayNames := append(weekdayNames[today:], weekdayNames[:today]...)
-
-	// Build mapping from weekday ID to column, with today as first column
-	weekdayToCol := make(map[uint32]int)
-	for i := range 7 {
-		// Weekday ID: 1=Mon, ..., 7=Sun
-		id := uint32((today+i)%7 + 1)
-		weekdayToCol[id] = i
+	// The timetable has the hours in the first column
+	first := 0
+	if c.view.Timetable {
+		first = 1
+		c.table.SetCell(0, 0, tview.NewTableCell("").SetSelectable(false).
+			SetBackgroundColor(ui.Styles.ContrastBackgroundColor))
 	}
-
 	// Header row: weekday names (today is first column)
-	for col, name := range rotatedWeekdayNames {
+	for col := range 7 {
+		name := now.AddDate(0, 0, col).Weekday().String()[:3]
 		color := ui.Styles.PrimaryTextColor
 		bgcolor := ui.Styles.ContrastBackgroundColor
 		attr := tcell.AttrNone
@@ -86,7 +293,7 @@ func (c *CalendarPage) render() {
 			attr = tcell.AttrBold
 			bgcolor = ui.Styles.MoreContrastBackgroundColor
 		}
-		c.table.SetCell(0, col, tview.NewTableCell(name).
+		c.table.SetCell(0, first+col, tview.NewTableCell(name).
 			SetTextColor(color).
 			SetBackgroundColor(bgcolor).
 			SetSelectable(true).
@@ -94,50 +301,10 @@ func (c *CalendarPage) render() {
 			SetAttributes(attr))
 	}
 
-	// Prepare anime lists for each weekday column
-	maxRows := 0
-	animeByCol := make([][]api.CalendarItem, 7)
-	for _, cal := range c.data {
-		col, ok := weekdayToCol[cal.Weekday.ID]
-		if !ok || col < 0 || col > 6 {
-			continue
-		}
-		// Sort by followers
-		sort.Slice(cal.Items, func(i, j int) bool {
-			fi := cal.Items[i].CollectionCount.Wish + cal.Items[i].CollectionCount.Watching + cal.Items[i].CollectionCount.Done
-			fj := cal.Items[j].CollectionCount.Wish + cal.Items[j].CollectionCount.Watching + cal.Items[j].CollectionCount.Done
-			return fi > fj
-		})
-		animeByCol[col] = cal.Items
-		if len(cal.Items) > maxRows {
-			maxRows = len(cal.Items)
-		}
-	}
-
-	// Fill table with anime rows (start from row 1)
-	for row := range maxRows {
-		for col := range 7 {
-			if row < len(animeByCol[col]) {
-				anime := animeByCol[col][row]
-				name := anime.Name
-				if anime.NameCn != "" {
-					name = anime.NameCn
-				}
-				// Limit name width
-				maxWidth := 10
-				runeName := []rune(name)
-				if len(runeName) > maxWidth {
-					name = string(runeName[:maxWidth-1]) + "…"
-				}
-				cell := tview.NewTableCell(name).
-					SetReference(anime.ID).
-					SetTextColor(ui.Styles.PrimaryTextColor).
-					SetAlign(tview.AlignLeft)
-				c.table.SetCell(row+1, col, cell)
-			} else {
-				c.table.SetCell(row+1, col, tview.NewTableCell("").SetSelectable(false))
-			}
-		}
+	if c.view.Timetable {
+		c.renderTimetable(c.entries(now))
+	} else {
+		c.renderWeek(c.entries(now))
 	}
 
 	c.table.SetSelectedFunc(func(row, column int) {
@@ -153,7 +320,11 @@ func (c *CalendarPage) render() {
 	})
 
 	footer := tview.NewTextView().SetDynamicColors(true).SetTextAlign(tview.AlignCenter)
-	footer.SetText("Space/Enter: 详情  ←/→ ↑/↓: 移动  ?: Help")
+	if c.data == nil {
+		footer.SetText("加载中...")
+	} else {
+		footer.SetText("Space/Enter: 详情  ←/→ ↑/↓: 移动  m: 我的  f: 只看我的  t: 时间表  ?: Help")
+	}
 
 	c.Clear()
 	c.AddItem(header, 0, 0, 1, 1, 0, 0, false).
@@ -161,25 +332,151 @@ func (c *CalendarPage) render() {
 		AddItem(footer, 2, 0, 1, 1, 0, 0, false)
 }
 
+// renderWeek lists the items of each day in a column
+func (c *CalendarPage) renderWeek(entries []calendarEntry) {
+	animeByCol := make([][]api.CalendarItem, 7)
+	maxRows := 0
+	for _, e := range entries {
+		animeByCol[e.column] = append(animeByCol[e.column], e.item)
+		maxRows = max(maxRows, len(animeByCol[e.column]))
+	}
+	c.fillRows(1, 0, maxRows, func(col, row int) *tview.TableCell {
+		if row < len(animeByCol[col]) {
+			return c.cell(animeByCol[col][row], "")
+		}
+		return nil
+	})
+}
+
+// renderTimetable puts the items in rows of the hour they start. Items of the same hour
+// and day are stacked in rows sorted by time. Items without a time are at the bottom.
+func (c *CalendarPage) renderTimetable(entries []calendarEntry) {
+	const unknown = 24
+	slots := make(map[int][][]calendarEntry) // Hour to items by column
+	for _, e := range entries {
+		hour := unknown
+		if e.hasTime {
+			hour = e.start.Hour()
+		}
+		if slots[hour] == nil {
+			slots[hour] = make([][]calendarEntry, 7)
+		}
+		slots[hour][e.column] = append(slots[hour][e.column], e)
+	}
+	hours := slices.Sorted(maps.Keys(slots))
+
+	row := 1
+	for _, hour := range hours {
+		columns := slots[hour]
+		rows := 0
+		for _, items := range columns {
+			sort.SliceStable(items, func(i, j int) bool {
+				return items[i].start.Hour()*60+items[i].start.Minute() < items[j].start.Hour()*60+items[j].start.Minute()
+			})
+			rows = max(rows, len(items))
+		}
+		label := fmt.Sprintf("%02d:00", hour)
+		if hour == unknown {
+			label = "--:--"
+		}
+		c.table.SetCell(row, 0, tview.NewTableCell(label).SetSelectable(false).SetTextColor(ui.GreyColor()))
+		for i := 1; i < rows; i++ {
+			c.table.SetCell(row+i, 0, tview.NewTableCell("").SetSelectable(false))
+		}
+		c.fillRows(row, 1, rows, func(col, i int) *tview.TableCell {
+			if i >= len(columns[col]) {
+				return nil
+			}
+			e := columns[col][i]
+			if !e.hasTime {
+				return c.cell(e.item, "")
+			}
+			return c.cell(e.item, e.start.Format("15:04")+" ")
+		})
+		row += rows
+	}
+}
+
+// fillRows sets rows of the 7 day columns from row and column first. A nil cell is left empty.
+func (c *CalendarPage) fillRows(row, first, rows int, cell func(col, row int) *tview.TableCell) {
+	for i := range rows {
+		for col := range 7 {
+			if content := cell(col, i); content != nil {
+				c.table.SetCell(row+i, first+col, content)
+			} else {
+				c.table.SetCell(row+i, first+col, tview.NewTableCell("").SetSelectable(false))
+			}
+		}
+	}
+}
+
+func (c *CalendarPage) navState() navState {
+	row, column := c.table.GetSelection()
+	offset, _ := c.table.GetOffset()
+	state := navState{Row: row, Column: column, Offset: offset}
+	if cell := c.table.GetCell(row, column); cell != nil {
+		state.SubjectID, _ = cell.GetReference().(int)
+	}
+	return state
+}
+
+// restoreNav selects the subject of the state. Columns move with the weekday,
+// so the position is used only if the subject is gone.
+func (c *CalendarPage) restoreNav(state navState) {
+	if c.data == nil {
+		c.pending = &state
+		return
+	}
+	for row := range c.table.GetRowCount() {
+		for column := range c.table.GetColumnCount() {
+			if id, ok := c.table.GetCell(row, column).GetReference().(int); ok && id == state.SubjectID {
+				c.table.Select(row, column)
+				return
+			}
+		}
+	}
+	if state.Row > 0 {
+		c.table.Select(state.Row, state.Column)
+		c.table.SetOffset(state.Offset, 0)
+	}
+}
+
+// openSelected opens the subject of the selected cell
+func (c *CalendarPage) openSelected() {
+	if cell := c.table.GetCell(c.table.GetSelection()); cell != nil && cell.GetReference() != nil {
+		if subjectID, ok := cell.GetReference().(int); ok {
+			c.app.OpenSubjectPage(subjectID, "calendar")
+		}
+	}
+}
+
 func (c *CalendarPage) setKeyBindings() {
+	doubleClickToOpen(c.table, c.openSelected)
+	c.table.SetSelectionChangedFunc(func(row, column int) {
+		c.fetchSchedule()
+	})
 	c.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyRune && event.Rune() == ' ' {
-			if cell := c.table.GetCell(c.table.GetSelection()); cell != nil && cell.GetReference() != nil {
-				if c.app != nil {
-					if subjectID, ok := cell.GetReference().(int); ok {
-						c.app.OpenSubjectPage(subjectID, "calendar")
-						return nil
-					}
-				}
-			}
+		switch action := c.app.keymap.Match(keymap.Calendar, event); action {
+		case keymap.Open:
+			c.openSelected()
+			return nil
+		case keymap.CalendarMine, keymap.CalendarHide, keymap.Timetable:
+			c.toggleView(action)
 			return nil
 		}
-		switch event.Rune() {
-		default:
-			if c.app != nil {
-				c.app.handlePageSwitch(event.Rune())
-			}
+		if c.app.handleGlobalKey(event) {
+			return nil
 		}
 		return event
 	})
 }
+
+func (c *CalendarPage) paletteSubjects() []paletteSubject {
+	var subjects []paletteSubject
+	for _, cal := range c.data {
+		for _, item := range cal.Items {
+			subjects = append(subjects, paletteSubject{ID: item.ID, Name: item.Name, NameCn: item.NameCn})
+		}
+	}
+	return subjects
+}
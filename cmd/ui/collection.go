@@ -1,15 +1,20 @@
 package tui
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"slices"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/iucario/bangumi-go/api"
 	"github.com/iucario/bangumi-go/cmd/list"
 	"github.com/iucario/bangumi-go/cmd/subject"
+	"github.com/iucario/bangumi-go/internal/config"
+	"github.com/iucario/bangumi-go/internal/keymap"
+	"github.com/iucario/bangumi-go/internal/loader"
 	"github.com/iucario/bangumi-go/internal/ui"
 	"github.com/rivo/tview"
 )
@@ -19,45 +24,59 @@ var PAGE_SIZE = 20
 type CollectionPage struct {
 	*tview.Flex
 	Name             string
+	Username         string // Owner of the collection
+	ReadOnly         bool   // Collections of other users can not be edited
 	CollectionStatus api.CollectionStatus
-	Collections      []api.UserSubjectCollection
+	Collections      []api.UserSubjectCollection // All loaded items in server order
+	Visible          []api.UserSubjectCollection // Filtered and sorted items in the list
 	Total            int
+	Query            string // Filter query, see ParseFilter
+	Sort             SortMode
 	app              *App
 	ListView         *tview.List
 	DetailView       *tview.TextView
-	CurrentSubject   int // Subject ID in selection
+	FilterInput      *tview.InputField
+	split            *ui.SplitPane
+	CurrentSubject   int          // Subject ID in selection
+	subjectView      *SubjectPage // Selected subject shown instead of the detail, nil if hidden
+	// Selection and scroll to restore when the list is loaded
+	restoreSubject int
+	restoreOffset  int
+	// Episode status before unconfirmed progress changes, by subject ID
+	pendingProgress map[uint32]uint32
+}
+
+// collectionView is the persisted filter and sort of a collection page
+type collectionView struct {
+	Query string   `json:"query"`
+	Sort  SortMode `json:"sort"`
 }
 
 // NewCollectionPage creates a list with detail page for a specific collection type.
 func NewCollectionPage(a *App, collectionStatus api.CollectionStatus) *CollectionPage {
-	userCollections, err := list.ListUserCollection(a.User.Client, list.UserListOptions{
-		CollectionType: collectionStatus,
-		Username:       a.User.Username,
-		SubjectType:    "all", // TODO: add filter feature
-		Limit:          PAGE_SIZE,
-		Offset:         0,
-	})
-	if err != nil {
-		slog.Error("Failed to fetch collections", "Error", err)
-		return nil
-	}
-	currentSubject := 0
-	if len(userCollections.Data) > 0 {
-		currentSubject = int(userCollections.Data[0].Subject.ID)
-	}
+	return newCollectionPage(a, collectionStatus.String(), a.User.Username, collectionStatus, false)
+}
+
+// NewUserCollectionPage creates a read-only collection page of any user.
+func NewUserCollectionPage(a *App, username string, collectionStatus api.CollectionStatus) *CollectionPage {
+	return newCollectionPage(a, "user", username, collectionStatus, true)
+}
+
+func newCollectionPage(a *App, name, username string, collectionStatus api.CollectionStatus, readOnly bool) *CollectionPage {
 	collectionPage := &CollectionPage{
 		Flex:             tview.NewFlex(),
 		app:              a,
-		Name:             collectionStatus.String(),
+		Name:             name,
+		Username:         username,
+		ReadOnly:         readOnly,
 		CollectionStatus: collectionStatus,
-		Collections:      userCollections.Data,
-		Total:            int(userCollections.Total),
-		ListView:         nil,
-		DetailView:       nil,
-		CurrentSubject:   currentSubject,
+		Sort:             SortUpdated,
+		pendingProgress:  make(map[uint32]uint32),
 	}
+	collectionPage.loadView()
 	collectionPage.render()
 	collectionPage.setKeyBindings()
+	collectionPage.Refresh()
 	return collectionPage
 }
 
@@ -65,33 +84,108 @@ func (c *CollectionPage) GetName() string {
 	return c.Name
 }
 
-// LoadData fetches and loads collection data asynchronously
-func (c *CollectionPage) LoadData() {
-	userCollections, err := list.ListUserCollection(c.app.User.Client, list.UserListOptions{
-		CollectionType: c.CollectionStatus,
-		Username:       c.app.User.Username,
-		SubjectType:    "all",
-		Limit:          PAGE_SIZE,
-		Offset:         0,
-	})
-	if err != nil {
-		slog.Error("Failed to fetch collections", "Error", err)
+func (c *CollectionPage) title() string {
+	name := string(c.CollectionStatus)
+	if c.ReadOnly {
+		name = fmt.Sprintf("%s %s", c.Username, c.CollectionStatus)
+	}
+	if c.app.loader.Loading(c.Name) {
+		return fmt.Sprintf("List %s (loading...)", name)
+	}
+	count := fmt.Sprintf("%d/%d", len(c.Collections), c.Total)
+	if len(c.Visible) != len(c.Collections) {
+		count = fmt.Sprintf("%d of %d/%d", len(c.Visible), len(c.Collections), c.Total)
+	}
+	return fmt.Sprintf("List %s (%s) by %s", name, count, c.Sort)
+}
+
+// viewKey is the state key of the filter and sort. Pages of other users are kept per user.
+func (c *CollectionPage) viewKey() string {
+	if c.ReadOnly {
+		return fmt.Sprintf("collection.%s.%s", c.Name, c.Username)
+	}
+	return "collection." + c.Name
+}
+
+// loadView restores filter and sort saved by a previous run
+func (c *CollectionPage) loadView() {
+	view := collectionView{Query: c.Query, Sort: c.Sort}
+	if err := config.LoadState(c.viewKey(), &view); err != nil {
+		slog.Error("Failed to load collection view", "Page", c.Name, "Error", err)
 		return
 	}
+	c.Query = view.Query
+	if slices.Contains(SORT_MODES, view.Sort) {
+		c.Sort = view.Sort
+	}
+}
 
-	c.Collections = userCollections.Data
-	c.Total = int(userCollections.Total)
-	if len(userCollections.Data) > 0 {
-		c.CurrentSubject = int(userCollections.Data[0].Subject.ID)
+func (c *CollectionPage) saveView() {
+	if err := config.SaveState(c.viewKey(), collectionView{Query: c.Query, Sort: c.Sort}); err != nil {
+		slog.Error("Failed to save collection view", "Page", c.Name, "Error", err)
 	}
+}
 
-	c.renderListItems()
-	c.renderDetail()
+// applyFilter computes visible items from all loaded items
+func (c *CollectionPage) applyFilter() {
+	filter, err := ParseFilter(c.Query)
+	if c.FilterInput != nil {
+		if err != nil {
+			c.FilterInput.SetFieldTextColor(ui.RedColor())
+		} else {
+			c.FilterInput.SetFieldTextColor(ui.Styles.PrimaryTextColor)
+		}
+	}
+	c.Visible = make([]api.UserSubjectCollection, 0, len(c.Collections))
+	for i := range c.Collections {
+		if filter.Match(&c.Collections[i]) {
+			c.Visible = append(c.Visible, c.Collections[i])
+		}
+	}
+	SortCollections(c.Visible, c.Sort)
+}
+
+func (c *CollectionPage) listOptions(offset int) list.UserListOptions {
+	return list.UserListOptions{
+		CollectionType: c.CollectionStatus,
+		Username:       c.Username,
+		SubjectType:    "all", // Filtered on the client
+		Limit:          PAGE_SIZE,
+		Offset:         offset,
+	}
+}
+
+// fetch loads a page of the collection in the background. apply is skipped on failure.
+func (c *CollectionPage) fetch(offset int, apply func(*api.UserCollections)) {
+	options := c.listOptions(offset)
+	loader.Load(c.app.loader, c.Name, func(ctx context.Context) (*api.UserCollections, error) {
+		return list.ListUserCollection(c.app.User.Client.WithContext(ctx), options)
+	}, func(collections *api.UserCollections, err error) {
+		if err == nil {
+			apply(collections)
+		} else if len(c.Collections) == 0 {
+			c.DetailView.SetText("加载失败, R: 重试")
+		}
+		c.ListView.SetTitle(c.title())
+		c.app.tabs.Update()
+	})
+	c.ListView.SetTitle(c.title())
 }
 
 func (c *CollectionPage) render() {
 	c.Clear()
-	c.ListView = tview.NewList()
+	c.FilterInput = tview.NewInputField().SetLabel("Filter: ").SetText(c.Query).
+		SetPlaceholder("/ to filter: type:anime tag:百合 rate:7-10 year:2020- title")
+	c.FilterInput.SetChangedFunc(func(text string) {
+		c.Query = text
+		c.renderListItems()
+		c.renderDetail()
+	})
+	c.FilterInput.SetDoneFunc(func(key tcell.Key) {
+		c.saveView()
+		c.app.SetFocus(c.ListView)
+	})
+	c.ListView = ui.NewList()
 	c.ListView.SetBorder(true).SetTitleAlign(tview.AlignLeft)
 	c.ListView.SetWrapAround(false)
 
@@ -101,67 +195,78 @@ func (c *CollectionPage) render() {
 	c.renderDetail()
 
 	// Scroll with j/k keys
-	c.ListView.SetInputCapture(handleScrollKeys(c.ListView))
-	c.DetailView.SetInputCapture(handleScrollKeys(c.DetailView))
+	c.ListView.SetInputCapture(c.app.handleScrollKeys(c.ListView))
+	c.DetailView.SetInputCapture(c.app.handleScrollKeys(c.DetailView))
 
 	c.ListView.SetChangedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
-		if index >= 0 && index < len(c.Collections) {
-			slog.Debug(fmt.Sprintf("Selected %s", c.Collections[index].Subject.Name))
-			c.CurrentSubject = int(c.Collections[index].Subject.ID)
+		if index >= 0 && index < len(c.Visible) {
+			slog.Debug(fmt.Sprintf("Selected %s", c.Visible[index].Subject.Name))
+			c.CurrentSubject = int(c.Visible[index].Subject.ID)
 			c.renderDetail()
 		}
 	})
 
 	// Open Subject page on click(enter/space)
-	c.ListView.SetSelectedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
-		if index >= 0 && index < len(c.Collections) {
-			subID := int(c.Collections[index].Subject.ID)
+	open := func(index int) {
+		if index >= 0 && index < len(c.Visible) {
+			subID := int(c.Visible[index].Subject.ID)
 			c.app.OpenSubjectPage(subID, c.Name)
 		}
+	}
+	c.ListView.SetSelectedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
+		open(index)
 	})
+	c.app.clickToSelect(c.ListView, 2, open)
 
 	// Change border color on focus/blur
 	c.ListView.SetFocusFunc(func() {
 		c.ListView.SetBorderColor(ui.Styles.TitleColor) // Focused color
 	})
 	c.ListView.SetBlurFunc(func() {
-		c.ListView.SetBorderColor(tcell.ColorGray) // Unfocused color
+		c.ListView.SetBorderColor(ui.Styles.BorderColor) // Unfocused color
 	})
 	c.DetailView.SetFocusFunc(func() {
 		c.DetailView.SetBorderColor(ui.Styles.TitleColor)
 	})
 	c.DetailView.SetBlurFunc(func() {
-		c.DetailView.SetBorderColor(tcell.ColorGray)
+		c.DetailView.SetBorderColor(ui.Styles.BorderColor)
 	})
 
+	c.split = c.app.newSplit("collection", c.ListView, c.DetailView)
 	c.Clear()
-	c.Flex.AddItem(c.ListView, 0, 2, true).
-		AddItem(c.DetailView, 0, 3, false)
+	c.Flex.SetDirection(tview.FlexRow).
+		AddItem(c.FilterInput, 1, 0, false).
+		AddItem(c.split, 0, 1, true)
 	c.Flex.SetFullScreen(false).SetBorderPadding(0, 0, 0, 0)
 }
 
+// Refresh reloads the first page of the collection in the background
 func (c *CollectionPage) Refresh() {
-	collections, err := list.ListUserCollection(c.app.User.Client, list.UserListOptions{
-		CollectionType: c.CollectionStatus,
-		Username:       c.app.User.Username,
-		SubjectType:    "all", // TODO: add filter feature
-		Limit:          PAGE_SIZE,
-		Offset:         0,
-	})
-	if err != nil {
-		slog.Error("Failed to refresh collections", "Error", err)
-		return
+	if len(c.Collections) == 0 {
+		c.DetailView.SetText("加载中...")
 	}
+	c.fetch(0, func(collections *api.UserCollections) {
+		c.Collections = collections.Data
+		c.Total = int(collections.Total)
+		// Keep the selection unless one is restored. A subject no longer listed selects the first item.
+		restore := c.restoreSubject != 0
+		if restore {
+			c.CurrentSubject = c.restoreSubject
+		}
+		c.renderListItems()
+		if restore {
+			c.ListView.SetOffset(c.restoreOffset, 0)
+		}
+		c.restoreSubject, c.restoreOffset = 0, 0
+		c.renderDetail()
+	})
+}
 
-	c.Collections = collections.Data
-	c.Total = int(collections.Total)
-	if len(collections.Data) > 0 {
-		c.CurrentSubject = int(collections.Data[0].Subject.ID)
-	} else {
-		c.CurrentSubject = 0
-	}
-	c.renderListItems()
-	c.renderDetail()
+// nextStatus switches to the next collection status and reloads the list
+func (c *CollectionPage) nextStatus() {
+	index := slices.Index(api.C_STATUS, c.CollectionStatus)
+	c.CollectionStatus = api.C_STATUS[(index+1)%len(api.C_STATUS)]
+	c.Refresh()
 }
 
 // LoadNextPage loads next page of a collection list
@@ -175,91 +280,159 @@ func (c *CollectionPage) LoadNextPage() {
 		c.app.Notify("No more items")
 		return
 	}
-	collections, err := list.ListUserCollection(c.app.User.Client, list.UserListOptions{
-		CollectionType: c.CollectionStatus,
-		Username:       c.app.User.Username,
-		SubjectType:    "all",
-		Limit:          PAGE_SIZE,
-		Offset:         size,
+	c.fetch(size, func(collections *api.UserCollections) {
+		// Update collections and list view
+		c.Collections = append(c.Collections, collections.Data...)
+		c.renderListItems()
 	})
-	if err != nil {
-		slog.Error("Failed to fetch collections", "Error", err)
-		return
-	}
-
-	// Update collections and list view
-	c.Collections = append(c.Collections, collections.Data...)
-	if c.ListView == nil {
-		slog.Error("List view not found")
-		return
-	}
-
-	// Add new items to list view
-	for _, collection := range collections.Data {
-		c.ListView.AddItem(collection.Name(), "", 0, nil)
-	}
-
-	// Update title to show progress
-	c.ListView.SetTitle(fmt.Sprintf("List %s (%d/%d)", c.CollectionStatus, len(c.Collections), c.Total))
 }
 
 func (c *CollectionPage) setKeyBindings() {
 	listView := c.ListView
-	detailView := c.DetailView
 	c.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyLeft:
+		// Keys are typed into the filter
+		if c.FilterInput.HasFocus() {
+			return event
+		}
+		switch c.app.keymap.Match(keymap.Collection, event) {
+		case keymap.FocusLeft:
 			c.app.SetFocus(listView)
-		case tcell.KeyRight:
-			c.app.SetFocus(detailView)
-		case tcell.KeyRune:
-			switch event.Rune() {
-			case 'l':
-				c.app.SetFocus(detailView)
-			case 'h':
-				c.app.SetFocus(listView)
-			case 'e':
-				slog.Debug("collect")
-				if len(c.Collections) == 0 {
-					slog.Warn("No collection to edit")
-					return event
-				}
-				index := listView.GetCurrentItem()
-				if index < 0 || index >= len(c.Collections) {
-					slog.Warn("Invalid collection index for edit")
-					return event
-				}
-				modal := NewCollectModal(c.app, &c.Collections[index], c.onSave)
-				if modal != nil {
-					c.app.Pages.AddPage("collect", modal, true, true)
-					c.app.SetFocus(modal)
-				} else {
-					c.app.NotifyWithStyle("collection is nil", "error")
-				}
-			case 'R':
-				c.Refresh()
-			case 'n':
-				c.LoadNextPage()
-			default:
-				c.app.handlePageSwitch(event.Rune())
+		case keymap.FocusRight:
+			c.app.SetFocus(c.split.Right())
+		case keymap.Maximize:
+			c.split.ToggleMaximize()
+		case keymap.Edit:
+			slog.Debug("collect")
+			if c.ReadOnly {
+				c.app.NotifyWithStyle(fmt.Sprintf("Collection of %s is read-only", c.Username), "warning")
+				return event
+			}
+			if len(c.Visible) == 0 {
+				slog.Warn("No collection to edit")
+				return event
+			}
+			index := listView.GetCurrentItem()
+			if index < 0 || index >= len(c.Visible) {
+				slog.Warn("Invalid collection index for edit")
+				return event
+			}
+			modal := NewCollectModal(c.app, &c.Visible[index], c.onSave)
+			if modal != nil {
+				c.app.Pages.AddPage("collect", modal, true, true)
+				c.app.SetFocus(modal)
+			} else {
+				c.app.NotifyWithStyle("collection is nil", "error")
+			}
+		case keymap.Refresh:
+			c.Refresh()
+		case keymap.SwitchStatus:
+			if c.ReadOnly {
+				c.nextStatus()
+			}
+		case keymap.NextPage:
+			c.LoadNextPage()
+		case keymap.EpisodeNext:
+			c.changeProgress(1)
+		case keymap.EpisodePrev:
+			c.changeProgress(-1)
+		case keymap.Filter:
+			c.app.SetFocus(c.FilterInput)
+			return nil
+		case keymap.SubjectSplit:
+			c.toggleSubject()
+		case keymap.Sort:
+			c.Sort = c.Sort.Next()
+			c.saveView()
+			c.renderListItems()
+			c.app.Notify(fmt.Sprintf("Sort by %s", c.Sort))
+		default:
+			if c.app.handleGlobalKey(event) {
+				return nil
 			}
 		}
 		return event
 	})
 }
 
-// Render the list view and detail view based on collection data
+// changeProgress marks the next episode watched (delta 1) or unmarks the last one (delta -1).
+// The page is updated at once and rolled back if the API fails.
+func (c *CollectionPage) changeProgress(delta int) {
+	if c.ReadOnly {
+		c.app.NotifyWithStyle(fmt.Sprintf("Collection of %s is read-only", c.Username), "warning")
+		return
+	}
+	collection := c.selectedCollection()
+	if collection == nil {
+		return
+	}
+	if collection.Subject.Type == uint32(api.SubjectMap["book"]) {
+		c.app.NotifyWithStyle("Book progress is edited with e", "warning")
+		return
+	}
+	id := collection.SubjectID
+	name := collection.Name()
+	episode := int(collection.EpStatus) + delta
+	if episode < 0 || (collection.Subject.Eps > 0 && episode > int(collection.Subject.Eps)) {
+		c.app.NotifyWithStyle(fmt.Sprintf("%s has no more episodes", name), "warning")
+		return
+	}
+	if _, ok := c.pendingProgress[id]; !ok {
+		c.pendingProgress[id] = collection.EpStatus
+	}
+	c.setEpStatus(id, uint32(episode))
+
+	// A newer change of the same subject cancels this one. The last change sets the final progress.
+	key := fmt.Sprintf("progress %d", id)
+	loader.Load(c.app.loader, key, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, subject.WatchToEpisode(c.app.User.Client.WithContext(ctx), int(id), episode)
+	}, func(_ struct{}, err error) {
+		original := c.pendingProgress[id]
+		delete(c.pendingProgress, id)
+		if err != nil {
+			c.setEpStatus(id, original)
+			return
+		}
+		c.app.NotifyWithStyle(fmt.Sprintf("%s: watched %d", name, episode), "success")
+	})
+}
+
+// setEpStatus updates the watched episodes of a subject on the page
+func (c *CollectionPage) setEpStatus(subjectID uint32, epStatus uint32) {
+	if index := indexOfCollection(c.Collections, subjectID); index >= 0 {
+		c.Collections[index].EpStatus = epStatus
+	}
+	if index := indexOfCollection(c.Visible, subjectID); index >= 0 {
+		c.Visible[index].EpStatus = epStatus
+	}
+	c.renderDetail()
+}
+
+// Render the list view with visible items. The selection is kept if it is still visible.
 func (c *CollectionPage) renderListItems() {
+	current := c.CurrentSubject
+	c.applyFilter()
 	c.ListView.Clear()
-	c.ListView.SetTitle(fmt.Sprintf("List %s (%d/%d)", c.CollectionStatus, len(c.Collections), c.Total))
+	c.ListView.SetTitle(c.title())
 
-	for _, collection := range c.Collections {
+	for _, collection := range c.Visible {
 		c.ListView.AddItem(collection.Name(), "", 0, nil)
 	}
+	if index := indexOfCollection(c.Visible, uint32(current)); index >= 0 {
+		c.ListView.SetCurrentItem(index)
+		c.CurrentSubject = current
+	} else if len(c.Visible) > 0 {
+		c.CurrentSubject = int(c.Visible[0].Subject.ID)
+	} else {
+		c.CurrentSubject = 0
+	}
 }
 
-// Render the detail view based on the current selection
+// Render the detail view based on the current selection.
+// The subject beside the list follows the selection.
 func (c *CollectionPage) renderDetail() {
+	if c.subjectView != nil && c.CurrentSubject != 0 && c.subjectView.ID != c.CurrentSubject {
+		c.showSubject(c.CurrentSubject)
+	}
 	currentIndex := indexOfCollection(c.Collections, uint32(c.CurrentSubject))
 	if 0 <= currentIndex && currentIndex < len(c.Collections) {
 		c.DetailView.SetText(createCollectionText(&c.Collections[currentIndex]))
@@ -286,11 +459,14 @@ func (c *CollectionPage) onSave(collection *api.UserSubjectCollection) error {
 	}
 	// Episode/volume status update
 	if EpisodeStatusChanged(&original, collection) {
-		subject.WatchToEpisode(c.app.User.Client, int(collection.SubjectID), int(collection.EpStatus))
+		if err := subject.WatchToEpisode(c.app.User.Client, int(collection.SubjectID), int(collection.EpStatus)); err != nil {
+			return err
+		}
 	}
 
 	c.Collections = toFrontItem(c.Collections, updatedIndex)
 	c.Collections[0] = *collection
+	c.Collections[0].UpdatedAt = time.Now()
 	c.renderListItems()
 	c.renderDetail()
 	// TODO: update other collection page if needed
@@ -298,18 +474,45 @@ func (c *CollectionPage) onSave(collection *api.UserSubjectCollection) error {
 	return nil
 }
 
+// toggleSubject shows the selected subject beside the list instead of the detail, or hides it
+func (c *CollectionPage) toggleSubject() {
+	if c.subjectView != nil {
+		focused := c.subjectView.HasFocus()
+		c.subjectView = nil
+		c.split.SetRight(c.DetailView)
+		if focused {
+			c.app.SetFocus(c.ListView)
+		}
+		return
+	}
+	if c.CurrentSubject == 0 {
+		return
+	}
+	c.showSubject(c.CurrentSubject)
+}
+
+// showSubject replaces the right pane with the page of a subject
+func (c *CollectionPage) showSubject(id int) {
+	focused := c.split.Right().HasFocus()
+	c.subjectView = c.app.subjectPage(id)
+	c.split.SetRight(c.subjectView)
+	if focused {
+		c.app.SetFocus(c.subjectView)
+	}
+}
+
 // Select a subject in the collection page by its ID.
 // Updates the list and detail views accordingly, and sets the selection field.
 func (c *CollectionPage) Select(subjectID int) {
 	slog.Debug(fmt.Sprintf("Select subject %d in collection page", subjectID))
-	index := indexOfCollection(c.Collections, uint32(subjectID))
-	if index < 0 || index >= len(c.Collections) {
+	index := indexOfCollection(c.Visible, uint32(subjectID))
+	if index < 0 || index >= len(c.Visible) {
 		slog.Error("Subject not found in the collection")
 		return
 	}
 	c.ListView.SetCurrentItem(index)
-	c.DetailView.SetText(createCollectionText(&c.Collections[index]))
-	c.CurrentSubject = index
+	c.DetailView.SetText(createCollectionText(&c.Visible[index]))
+	c.CurrentSubject = subjectID
 	c.app.SetFocus(c.ListView)
 }
 
@@ -375,15 +578,41 @@ func toFrontItem(collections []api.UserSubjectCollection, index int) []api.UserS
 	return newSlice
 }
 
-// handleScrollKeys captures input events for the Box and handles 'j' and 'k' keys.
-func handleScrollKeys(b tview.Primitive) func(event *tcell.EventKey) *tcell.EventKey {
-	return func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Rune() {
-		case 'j':
-			b.InputHandler()(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone), nil)
-		case 'k':
-			b.InputHandler()(tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone), nil)
-		}
-		return event
+func (c *CollectionPage) navState() navState {
+	offset, _ := c.ListView.GetOffset()
+	state := navState{SubjectID: c.CurrentSubject, Offset: offset}
+	if c.ReadOnly {
+		state.Username = c.Username
+		state.Status = c.CollectionStatus
+	}
+	return state
+}
+
+// restoreNav selects the subject of the state now, or when the list is loaded
+func (c *CollectionPage) restoreNav(state navState) {
+	if len(c.Collections) == 0 || c.app.loader.Loading(c.Name) {
+		c.restoreSubject, c.restoreOffset = state.SubjectID, state.Offset
+		return
+	}
+	c.CurrentSubject = state.SubjectID
+	c.renderListItems()
+	c.ListView.SetOffset(state.Offset, 0)
+	c.renderDetail()
+}
+
+// selectedCollection returns the collection in selection. nil for read-only pages.
+func (c *CollectionPage) selectedCollection() *api.UserSubjectCollection {
+	index := c.ListView.GetCurrentItem()
+	if c.ReadOnly || index < 0 || index >= len(c.Visible) {
+		return nil
+	}
+	return &c.Visible[index]
+}
+
+func (c *CollectionPage) paletteSubjects() []paletteSubject {
+	subjects := make([]paletteSubject, len(c.Collections))
+	for i, collection := range c.Collections {
+		subjects[i] = paletteSubject{ID: int(collection.SubjectID), Name: collection.Subject.Name, NameCn: collection.Subject.NameCn}
 	}
+	return subjects
 }
@@ -0,0 +1,239 @@
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/iucario/bangumi-go/api"
+)
+
+// SortMode is the order of items on a collection page
+type SortMode string
+
+const (
+	SortUpdated  SortMode = "updated"
+	SortRate     SortMode = "rate"
+	SortScore    SortMode = "score"
+	SortRank     SortMode = "rank"
+	SortDate     SortMode = "date"
+	SortProgress SortMode = "progress"
+)
+
+var SORT_MODES = []SortMode{SortUpdated, SortRate, SortScore, SortRank, SortDate, SortProgress}
+
+// Next returns the sort mode after m, wrapping around
+func (m SortMode) Next() SortMode {
+	index := slices.Index(SORT_MODES, m)
+	return SORT_MODES[(index+1)%len(SORT_MODES)]
+}
+
+// CollectionFilter filters loaded collections on the client.
+// It is parsed from a query such as "type:anime tag:百合 rate:7-10 year:2020- 魔法".
+// Words without a prefix are matched fuzzily against titles.
+type CollectionFilter struct {
+	SubjectType int      // 0 for any
+	Tags        []string // Each must be in our tags or community tags
+	MinRate     uint32
+	MaxRate     uint32 // 0 for no limit
+	MinYear     int
+	MaxYear     int // 0 for no limit
+	Words       []string
+}
+
+// ParseFilter parses a filter query. Invalid terms are returned as an error
+// together with the filter of the valid terms.
+func ParseFilter(query string) (CollectionFilter, error) {
+	f := CollectionFilter{}
+	var invalid []string
+	for _, term := range strings.Fields(query) {
+		key, value, found := strings.Cut(term, ":")
+		if !found || value == "" {
+			f.Words = append(f.Words, strings.ToLower(term))
+			continue
+		}
+		switch key {
+		case "type":
+			t, ok := api.SubjectMap[value]
+			if !ok {
+				invalid = append(invalid, term)
+				continue
+			}
+			f.SubjectType = t
+		case "tag":
+			f.Tags = append(f.Tags, value)
+		case "rate":
+			low, high, err := parseRange(value)
+			if err != nil {
+				invalid = append(invalid, term)
+				continue
+			}
+			f.MinRate, f.MaxRate = uint32(low), uint32(high)
+		case "year":
+			low, high, err := parseRange(value)
+			if err != nil {
+				invalid = append(invalid, term)
+				continue
+			}
+			f.MinYear, f.MaxYear = low, high
+		default:
+			f.Words = append(f.Words, strings.ToLower(term))
+		}
+	}
+	if len(invalid) > 0 {
+		return f, fmt.Errorf("invalid filter: %s", strings.Join(invalid, " "))
+	}
+	return f, nil
+}
+
+// parseRange parses "a-b", "a-", "-b" or "a". A missing upper bound is 0.
+func parseRange(s string) (int, int, error) {
+	lowText, highText, isRange := strings.Cut(s, "-")
+	if !isRange {
+		highText = lowText
+	}
+	var low, high int
+	var err error
+	if lowText != "" {
+		if low, err = strconv.Atoi(lowText); err != nil {
+			return 0, 0, err
+		}
+	}
+	if highText != "" {
+		if high, err = strconv.Atoi(highText); err != nil {
+			return 0, 0, err
+		}
+	}
+	if low < 0 || high < 0 || (high > 0 && low > high) {
+		return 0, 0, fmt.Errorf("invalid range %s", s)
+	}
+	return low, high, nil
+}
+
+// IsEmpty reports whether the filter matches everything
+func (f CollectionFilter) IsEmpty() bool {
+	return f.SubjectType == 0 && len(f.Tags) == 0 && f.MinRate == 0 && f.MaxRate == 0 &&
+		f.MinYear == 0 && f.MaxYear == 0 && len(f.Words) == 0
+}
+
+// Match reports whether a collection passes every term of the filter
+func (f CollectionFilter) Match(c *api.UserSubjectCollection) bool {
+	if f.SubjectType != 0 && int(c.Subject.Type) != f.SubjectType {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !hasTag(c, tag) {
+			return false
+		}
+	}
+	if c.Rate < f.MinRate || (f.MaxRate > 0 && c.Rate > f.MaxRate) {
+		return false
+	}
+	if f.MinYear > 0 || f.MaxYear > 0 {
+		year := airYear(c)
+		if year == 0 || year < f.MinYear || (f.MaxYear > 0 && year > f.MaxYear) {
+			return false
+		}
+	}
+	for _, word := range f.Words {
+		if !fuzzyMatch(word, c.Subject.Name) && !fuzzyMatch(word, c.Subject.NameCn) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasTag(c *api.UserSubjectCollection, tag string) bool {
+	if slices.Contains(c.Tags, tag) {
+		return true
+	}
+	return slices.ContainsFunc(c.Subject.Tags, func(t api.Tag) bool {
+		return t.Name == tag
+	})
+}
+
+// airYear returns the year of the air date. 0 if unknown.
+func airYear(c *api.UserSubjectCollection) int {
+	if len(c.Subject.Date) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(c.Subject.Date[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
+
+// fuzzyMatch reports whether the runes of pattern appear in text in order.
+// pattern should be lower case. Spaces in text are ignored.
+func fuzzyMatch(pattern, text string) bool {
+	_, ok := fuzzyScore(pattern, text)
+	return ok
+}
+
+// fuzzyScore matches like fuzzyMatch and scores the match.
+// Consecutive runes and a match at the start score higher.
+func fuzzyScore(pattern, text string) (int, bool) {
+	target := []rune(pattern)
+	i := 0
+	score := 0
+	last := -2
+	pos := 0
+	for _, r := range strings.ToLower(text) {
+		if i == len(target) {
+			break
+		}
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if r == target[i] {
+			score++
+			if pos == last+1 {
+				score += 3
+			}
+			if pos == 0 {
+				score += 5
+			}
+			last = pos
+			i++
+		}
+		pos++
+	}
+	return score, i == len(target)
+}
+
+// progress is the ratio of watched episodes. Unknown totals sort by count.
+func progress(c *api.UserSubjectCollection) float64 {
+	if c.Subject.Eps == 0 {
+		return float64(c.EpStatus)
+	}
+	return float64(c.EpStatus) / float64(c.Subject.Eps)
+}
+
+// SortCollections sorts in place. Best first: latest, highest rated, top ranked, newest or furthest.
+// Missing values such as no rank or no date go last. The sort is stable so ties keep server order.
+func SortCollections(collections []api.UserSubjectCollection, mode SortMode) {
+	slices.SortStableFunc(collections, func(a, b api.UserSubjectCollection) int {
+		switch mode {
+		case SortRate:
+			return cmp.Compare(b.Rate, a.Rate)
+		case SortScore:
+			return cmp.Compare(b.Subject.Score, a.Subject.Score)
+		case SortRank:
+			// Unranked subjects have rank 0
+			if a.Subject.Rank == 0 || b.Subject.Rank == 0 {
+				return cmp.Compare(b.Subject.Rank, a.Subject.Rank)
+			}
+			return cmp.Compare(a.Subject.Rank, b.Subject.Rank)
+		case SortDate:
+			return cmp.Compare(b.Subject.Date, a.Subject.Date)
+		case SortProgress:
+			return cmp.Compare(progress(&b), progress(&a))
+		default:
+			return b.UpdatedAt.Compare(a.UpdatedAt)
+		}
+	})
+}
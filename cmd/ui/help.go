@@ -2,8 +2,11 @@ package tui
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/iucario/bangumi-go/internal/config"
+	"github.com/iucario/bangumi-go/internal/keymap"
 	"github.com/iucario/bangumi-go/internal/ui"
 	"github.com/rivo/tview"
 )
@@ -14,39 +17,12 @@ type Help struct {
 }
 
 func NewHelpPage(app *App) *Help {
-	text := `
-    Welcome to Bangumi TUI
-    <https://github.com/iucario/bangumi-go>
-
-    Shortcuts:
-
-    [%s]Pages[-]                               [%s]General[-]
-    1: Go to watching list              e: Edit collection
-    2: Go to wish list                  R: Refresh list
-    3: Go to done list                  n: Load next page
-    4: Go to stashed list               p: Load previous page
-    5: Go to dropped list               Space/Enter: View subject
-    6: Go to calendar
-    7: Go to search         
-    0: Go to user info (not yet)
-    ?: Show this help
-
-    [%s]Navigation[-]
-    j/up: Move up
-    k/down: Move down
-    h/left: Switch to left
-    l/right: Switch to right
-    Q: Quit
-    q/Esc: Back
-    `
-	color := ui.ColorToHex(ui.Styles.TertiaryTextColor)
-	colorText := fmt.Sprintf(text, color, color, color)
-	view := tview.NewTextView().SetText(colorText).SetDynamicColors(true)
+	view := tview.NewTextView().SetText(helpText(app.keymap)).SetDynamicColors(true)
 	view.SetWrap(false)
 
 	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyRune {
-			app.handlePageSwitch(event.Rune())
+		if app.handleGlobalKey(event) {
+			return nil
 		}
 		return event
 	})
@@ -59,3 +35,26 @@ func NewHelpPage(app *App) *Help {
 func (h *Help) GetName() string {
 	return "help"
 }
+
+// helpText lists the keys of every action, grouped by scope
+func helpText(km *keymap.Keymap) string {
+	var b strings.Builder
+	b.WriteString("\n    Welcome to Bangumi TUI\n    <https://github.com/iucario/bangumi-go>\n\n")
+	fmt.Fprintf(&b, "    Shortcuts from %s\n", tview.Escape(config.Path()))
+	color := ui.ColorTag(ui.Styles.TertiaryTextColor)
+	for _, scope := range keymap.Scopes {
+		fmt.Fprintf(&b, "\n    [%s]%s[-]\n", color, scope.Name)
+		for _, action := range scope.Actions {
+			keys := km.Keys(action)
+			names := make([]string, len(keys))
+			for i, key := range keys {
+				names[i] = keymap.DisplayName(key)
+			}
+			if len(names) == 0 {
+				names = []string{"(unbound)"}
+			}
+			fmt.Fprintf(&b, "    %-20s %s\n", tview.Escape(strings.Join(names, "/")), keymap.Descriptions[action])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package tui
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/internal/config"
+)
+
+// maxHistory limits the back and forward stacks
+const maxHistory = 100
+
+// navState is where a page was left: which subject or user it showed,
+// the selection and the scroll position. It is saved in history and between runs.
+type navState struct {
+	Page      string               `json:"page"`
+	SubjectID int                  `json:"subject_id,omitempty"` // Subject of the subject page, or selected in a list
+	Username  string               `json:"username,omitempty"`   // Owner of the user page
+	Status    api.CollectionStatus `json:"status,omitempty"`     // Status of the user page
+	Row       int                  `json:"row,omitempty"`        // Selected row of a table
+	Column    int                  `json:"column,omitempty"`
+	Offset    int                  `json:"offset,omitempty"` // First visible row or line
+}
+
+// navigable is a page that can save and restore its navigation state
+type navigable interface {
+	navState() navState
+	restoreNav(state navState)
+}
+
+// stateOf returns the state of a page. Pages without state only have a name.
+func (a *App) stateOf(name string) navState {
+	if page, ok := a.pages[name].(navigable); ok {
+		state := page.navState()
+		state.Page = name
+		return state
+	}
+	return navState{Page: name}
+}
+
+// PushPage adds the state of a page to the back history. A new navigation clears the forward history.
+func (a *App) PushPage(name string) {
+	a.history = pushState(a.history, a.stateOf(name))
+	a.forward = nil
+}
+
+func pushState(stack []navState, state navState) []navState {
+	stack = append(stack, state)
+	if len(stack) > maxHistory {
+		stack = slices.Delete(stack, 0, len(stack)-maxHistory)
+	}
+	return stack
+}
+
+// PopPage removes and returns the last state from the back history
+func (a *App) PopPage() (navState, bool) {
+	if len(a.history) == 0 {
+		return navState{}, false
+	}
+	index := len(a.history) - 1
+	state := a.history[index]
+	a.history = slices.Delete(a.history, index, index+1)
+	return state, true
+}
+
+// GoBack restores the previous page if there is a history, else does nothing
+func (a *App) GoBack() {
+	if prev, ok := a.PopPage(); ok {
+		a.forward = pushState(a.forward, a.stateOf(a.currentPage))
+		a.restore(prev)
+	}
+}
+
+// GoForward returns to the page left by GoBack
+func (a *App) GoForward() {
+	if len(a.forward) == 0 {
+		return
+	}
+	index := len(a.forward) - 1
+	next := a.forward[index]
+	a.forward = slices.Delete(a.forward, index, index+1)
+	a.history = pushState(a.history, a.stateOf(a.currentPage))
+	a.restore(next)
+}
+
+// restore shows the page of a state. The subject page is taken from the open subjects,
+// and the user page is created again if it shows another user.
+func (a *App) restore(state navState) {
+	switch state.Page {
+	case "subject":
+		if page, ok := a.pages["subject"].(*SubjectPage); !ok || page.ID != state.SubjectID {
+			if state.SubjectID == 0 {
+				return
+			}
+			a.addPage(a.subjectPage(state.SubjectID))
+		}
+	case "user":
+		page, ok := a.pages["user"].(*CollectionPage)
+		if !ok || page.Username != state.Username || page.CollectionStatus != state.Status {
+			if state.Username == "" {
+				return
+			}
+			a.addPage(NewUserCollectionPage(a, state.Username, state.Status))
+		}
+	}
+	if _, ok := a.pages[state.Page]; !ok {
+		slog.Warn("Can not restore page", "Page", state.Page)
+		return
+	}
+	a.show(state.Page)
+	if page, ok := a.pages[state.Page].(navigable); ok {
+		page.restoreNav(state)
+	}
+}
+
+// restoreLastSession opens the page left when the app was closed, or the calendar
+func (a *App) restoreLastSession() {
+	state := navState{Page: "calendar"}
+	if err := config.LoadState("navigation", &state); err != nil {
+		slog.Error("Failed to load navigation state", "error", err)
+	}
+	a.restore(state)
+	if a.currentPage == "" {
+		a.show("calendar")
+	}
+	if a.currentPage != "calendar" {
+		a.history = []navState{a.stateOf("calendar")}
+	}
+}
+
+// saveSession saves the current page to open it next time
+func (a *App) saveSession() {
+	if a.currentPage == "" || slices.Contains(MODALS, a.currentPage) {
+		return
+	}
+	if err := config.SaveState("navigation", a.stateOf(a.currentPage)); err != nil {
+		slog.Error("Failed to save navigation state", "error", err)
+	}
+}
@@ -0,0 +1,77 @@
+package tui
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/iucario/bangumi-go/internal/config"
+	"github.com/iucario/bangumi-go/internal/ui"
+	"github.com/rivo/tview"
+)
+
+// Default shares of the left pane of split pages
+var defaultSplits = map[string]float64{
+	"collection": 0.4,
+	"subject":    0.33,
+}
+
+// newSplit creates a split pane with the ratio saved in state.json.
+// Dragging the border saves the new ratio for all pages of the kind.
+func (a *App) newSplit(kind string, left, right tview.Primitive) *ui.SplitPane {
+	ratios := make(map[string]float64)
+	if err := config.LoadState("splits", &ratios); err != nil {
+		a.NotifyWithStyle("Failed to load layout: "+err.Error(), "error")
+	}
+	ratio, ok := ratios[kind]
+	if !ok {
+		ratio = defaultSplits[kind]
+	}
+	split := ui.NewSplitPane(left, right, ratio)
+	split.SetChangedFunc(func(ratio float64) {
+		ratios := make(map[string]float64)
+		_ = config.LoadState("splits", &ratios)
+		ratios[kind] = ratio
+		if err := config.SaveState("splits", ratios); err != nil {
+			a.NotifyWithStyle("Failed to save layout: "+err.Error(), "error")
+		}
+	})
+	return split
+}
+
+// clickToSelect makes a click select a list item and a double click open it.
+// tview opens items on a single click. rowsPerItem is 2 if secondary texts are shown.
+func (a *App) clickToSelect(list *tview.List, rowsPerItem int, open func(index int)) {
+	list.SetMouseCapture(func(action tview.MouseAction, event *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+		if !list.InRect(event.Position()) {
+			return action, event
+		}
+		switch action {
+		case tview.MouseLeftClick:
+			_, y := event.Position()
+			_, top, _, height := list.GetInnerRect()
+			offset, _ := list.GetOffset()
+			index := offset + (y-top)/rowsPerItem
+			a.SetFocus(list)
+			if y >= top && y < top+height && index < list.GetItemCount() {
+				list.SetCurrentItem(index)
+			}
+			return tview.MouseConsumed, nil
+		case tview.MouseLeftDoubleClick:
+			if list.GetItemCount() > 0 {
+				open(list.GetCurrentItem())
+			}
+			return tview.MouseConsumed, nil
+		}
+		return action, event
+	})
+}
+
+// doubleClickToOpen opens the selected cell of a table on a double click.
+// A single click selects cells.
+func doubleClickToOpen(table *tview.Table, open func()) {
+	table.SetMouseCapture(func(action tview.MouseAction, event *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+		if action == tview.MouseLeftDoubleClick && table.InRect(event.Position()) {
+			open()
+			return tview.MouseConsumed, nil
+		}
+		return action, event
+	})
+}
@@ -0,0 +1,307 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/internal/ui"
+	"github.com/iucario/bangumi-go/util"
+	"github.com/rivo/tview"
+)
+
+const paletteLimit = 100
+
+// PaletteItem is an action or a subject in the command palette
+type PaletteItem struct {
+	Label  string
+	Detail string
+	Run    func()
+}
+
+// paletteSubject is a subject a page has loaded. Also saved as a recently viewed subject.
+type paletteSubject struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	NameCn string `json:"name_cn"`
+}
+
+// label has both names to match either
+func (s paletteSubject) label() string {
+	if s.NameCn != "" && s.NameCn != s.Name {
+		return s.NameCn + " " + s.Name
+	}
+	return s.Name
+}
+
+// subjectSource is a page holding subjects that can be opened from the palette
+type subjectSource interface {
+	paletteSubjects() []paletteSubject
+}
+
+// collectionEditor is a page with a selected collection that can be saved
+type collectionEditor interface {
+	selectedCollection() *api.UserSubjectCollection
+	onSave(collection *api.UserSubjectCollection) error
+}
+
+// Palette is a fuzzy finder over actions and subjects loaded by pages.
+type Palette struct {
+	*tview.Flex
+	app     *App
+	input   *tview.InputField
+	list    *tview.List
+	items   []PaletteItem
+	matches []PaletteItem
+}
+
+func NewPalette(a *App, title string, items []PaletteItem) *Palette {
+	p := &Palette{
+		app:   a,
+		input: tview.NewInputField().SetLabel("> "),
+		list:  ui.NewList().ShowSecondaryText(false).SetHighlightFullLine(true),
+		items: items,
+	}
+	frame := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(p.input, 1, 0, true).
+		AddItem(p.list, 0, 1, false)
+	frame.SetBorder(true).SetTitle(title).SetTitleAlign(tview.AlignLeft)
+	frame.SetBorderColor(ui.Styles.TitleColor)
+
+	// Center the frame on the screen
+	p.Flex = tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(frame, 20, 0, true).
+			AddItem(nil, 0, 1, false), 70, 0, true).
+		AddItem(nil, 0, 1, false)
+
+	p.list.SetSelectedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
+		p.run(index)
+	})
+	p.input.SetChangedFunc(func(text string) {
+		p.update(text)
+	})
+	p.input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			p.Close()
+			return nil
+		case tcell.KeyEnter:
+			p.run(p.list.GetCurrentItem())
+			return nil
+		case tcell.KeyDown, tcell.KeyCtrlN, tcell.KeyTab:
+			p.list.SetCurrentItem((p.list.GetCurrentItem() + 1) % max(1, p.list.GetItemCount()))
+			return nil
+		case tcell.KeyUp, tcell.KeyCtrlP, tcell.KeyBacktab:
+			p.list.SetCurrentItem(max(0, p.list.GetCurrentItem()-1))
+			return nil
+		}
+		return event
+	})
+	p.update("")
+	return p
+}
+
+func (p *Palette) GetName() string {
+	return "palette"
+}
+
+// update lists items matching the query, best matches first
+func (p *Palette) update(query string) {
+	pattern := strings.ReplaceAll(strings.ToLower(query), " ", "")
+	type scored struct {
+		item  PaletteItem
+		score int
+	}
+	var matches []scored
+	for _, item := range p.items {
+		if score, ok := fuzzyScore(pattern, item.Label); ok {
+			matches = append(matches, scored{item, score})
+		}
+	}
+	// Stable so actions stay before subjects on ties
+	slices.SortStableFunc(matches, func(a, b scored) int {
+		return b.score - a.score
+	})
+
+	p.matches = p.matches[:0]
+	p.list.Clear()
+	for _, m := range matches[:min(len(matches), paletteLimit)] {
+		p.matches = append(p.matches, m.item)
+		text := fmt.Sprintf("%s %s", tview.Escape(m.item.Label), ui.Grey(tview.Escape(m.item.Detail)))
+		p.list.AddItem(text, "", 0, nil)
+	}
+}
+
+func (p *Palette) run(index int) {
+	if index < 0 || index >= len(p.matches) {
+		return
+	}
+	item := p.matches[index]
+	p.Close()
+	item.Run()
+}
+
+func (p *Palette) Close() {
+	p.app.Pages.RemovePage("palette")
+	p.app.SetFocus(p.app.Pages)
+}
+
+// OpenPalette opens the command palette over the current page
+func (a *App) OpenPalette() {
+	a.openPalette("Command palette", a.paletteItems())
+}
+
+// openPalette opens a palette of items over the current page
+func (a *App) openPalette(title string, items []PaletteItem) {
+	if a.Pages.HasPage("palette") {
+		return
+	}
+	palette := NewPalette(a, title, items)
+	a.Pages.AddPage("palette", palette, true, true)
+	a.SetFocus(palette)
+}
+
+// paletteItems lists actions for the current page followed by every loaded and recently viewed subject.
+func (a *App) paletteItems() []PaletteItem {
+	var items []PaletteItem
+	for _, name := range []string{"watching", "wish", "done", "stashed", "dropped", "calendar", "season", "search", "stats", "backlog", "help"} {
+		items = append(items, PaletteItem{Label: "Go to " + name, Detail: "page", Run: func() { a.Goto(name) }})
+	}
+	items = append(items,
+		PaletteItem{Label: "Open user collection", Detail: "page", Run: a.OpenUserModal},
+		PaletteItem{Label: "Recently viewed subjects", Detail: "page", Run: a.OpenRecent},
+		PaletteItem{Label: "Quit", Detail: "app", Run: a.Stop},
+	)
+	for _, name := range ui.ThemeNames(a.themes) {
+		if name == ui.CurrentTheme {
+			continue
+		}
+		items = append(items, PaletteItem{
+			Label:  "Theme " + name,
+			Detail: "app",
+			Run: func() {
+				if err := a.SetTheme(name); err != nil {
+					a.NotifyWithStyle(err.Error(), "error")
+					return
+				}
+				a.Notify("Theme " + name)
+			},
+		})
+	}
+
+	prevPage := a.currentPage
+	if editor, ok := a.pages[a.currentPage].(collectionEditor); ok {
+		if c := editor.selectedCollection(); c != nil {
+			items = append(items, a.collectionActions(editor, *c)...)
+		}
+	}
+
+	seen := make(map[int]bool)
+	for _, name := range a.Pages.GetPageNames(false) {
+		source, ok := a.pages[name].(subjectSource)
+		if !ok {
+			continue
+		}
+		for _, s := range source.paletteSubjects() {
+			if seen[s.ID] {
+				continue
+			}
+			seen[s.ID] = true
+			items = append(items, PaletteItem{
+				Label:  s.label(),
+				Detail: fmt.Sprintf("subject %d", s.ID),
+				Run:    func() { a.OpenSubjectPage(s.ID, prevPage) },
+			})
+		}
+	}
+	for _, s := range loadRecent() {
+		if seen[s.ID] {
+			continue
+		}
+		seen[s.ID] = true
+		items = append(items, PaletteItem{
+			Label:  s.label(),
+			Detail: fmt.Sprintf("recent subject %d", s.ID),
+			Run:    func() { a.OpenSubjectPage(s.ID, prevPage) },
+		})
+	}
+	return items
+}
+
+// collectionActions edits the selected collection of a page
+func (a *App) collectionActions(editor collectionEditor, c api.UserSubjectCollection) []PaletteItem {
+	name := c.Name()
+	save := func(updated api.UserSubjectCollection, message string) {
+		if err := editor.onSave(&updated); err != nil {
+			a.NotifyWithStyle(fmt.Sprintf("Failed to save %s: %v", name, err), "error")
+			return
+		}
+		a.NotifyWithStyle(message, "success")
+	}
+	items := []PaletteItem{{
+		Label:  "Open in browser",
+		Detail: name,
+		Run: func() {
+			if err := util.OpenBrowser(fmt.Sprintf("https://bgm.tv/subject/%d", c.SubjectID)); err != nil {
+				a.NotifyWithStyle(err.Error(), "error")
+			}
+		},
+	}}
+	for _, status := range api.C_STATUS {
+		if status == c.GetStatus() {
+			continue
+		}
+		items = append(items, PaletteItem{
+			Label:  "Set status " + string(status),
+			Detail: name,
+			Run: func() {
+				updated := c
+				updated.Type = uint32(api.CollectionType[status])
+				save(updated, fmt.Sprintf("%s: %s", name, status))
+			},
+		})
+	}
+	// Episodes and rates need an existing collection
+	if c.Type == 0 {
+		return items
+	}
+	if c.Subject.Eps == 0 || c.EpStatus < c.Subject.Eps {
+		items = append(items, PaletteItem{
+			Label:  "Mark next episode",
+			Detail: fmt.Sprintf("%s %d", name, c.EpStatus+1),
+			Run: func() {
+				updated := c
+				updated.EpStatus++
+				save(updated, fmt.Sprintf("%s: watched episode %d", name, updated.EpStatus))
+			},
+		})
+	}
+	for rate := uint32(10); rate >= 1; rate-- {
+		items = append(items, PaletteItem{
+			Label:  fmt.Sprintf("Rate %d", rate),
+			Detail: name,
+			Run: func() {
+				updated := c
+				updated.Rate = rate
+				save(updated, fmt.Sprintf("%s: rated %d", name, rate))
+			},
+		})
+	}
+	if c.Rate > 0 {
+		items = append(items, PaletteItem{
+			Label:  "Clear rate",
+			Detail: name,
+			Run: func() {
+				updated := c
+				updated.Rate = 0
+				save(updated, fmt.Sprintf("%s: rate cleared", name))
+			},
+		})
+	}
+	return items
+}
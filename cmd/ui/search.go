@@ -1,12 +1,15 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/iucario/bangumi-go/api"
 	"github.com/iucario/bangumi-go/cmd/search"
+	"github.com/iucario/bangumi-go/internal/keymap"
+	"github.com/iucario/bangumi-go/internal/loader"
 	"github.com/iucario/bangumi-go/internal/ui"
 	"github.com/rivo/tview"
 )
@@ -53,7 +56,7 @@ func NewSearchPage(app *App) *SearchPage {
 		Grid:           tview.NewGrid(),
 		client:         app.User.Client.HTTPClient,
 		app:            app,
-		table:          tview.NewTable(),
+		table:          ui.NewTable(),
 		searchInput:    tview.NewInputField().SetLabel("关键词: ").SetFieldWidth(40),
 		tagInput:       tview.NewInputField().SetLabel("标签  : ").SetFieldWidth(40),
 		startDateInput: startDate,
@@ -71,32 +74,49 @@ func NewSearchPage(app *App) *SearchPage {
 
 // search fetches data and render the ui accordingly
 func (p *SearchPage) search() {
-	p.statusBar.SetMessage("Searching...", "info")
 	p.currentPage = 1 // Always reset to first page on new search
 	p.fetchData()
-	maxPage := 1
-	if p.pageSize > 0 {
-		maxPage = (p.totalResults + p.pageSize - 1) / p.pageSize
-	}
-	statusMsg := fmt.Sprintf("Found %d results (Page %d/%d)", p.totalResults, p.currentPage, maxPage)
-	p.statusBar.SetMessage(statusMsg, "success")
-	p.render()
 }
 
 // paginateSearch fetches data and renders the UI for the current page (without resetting page number)
 func (p *SearchPage) paginateSearch() {
-	p.statusBar.SetMessage("Searching...", "info")
 	p.fetchData()
-	maxPage := 1
-	if p.pageSize > 0 {
-		maxPage = (p.totalResults + p.pageSize - 1) / p.pageSize
-	}
-	statusMsg := fmt.Sprintf("Found %d results (Page %d/%d)", p.totalResults, p.currentPage, maxPage)
-	p.statusBar.SetMessage(statusMsg, "success")
-	p.render()
 }
 
+// fetchData searches in the background. A new search cancels the pending one.
 func (p *SearchPage) fetchData() {
+	payload, ok := p.payload()
+	if !ok {
+		p.statusBar.SetMessage("Please enter a search query or tags", "warning")
+		return
+	}
+	p.statusBar.SetMessage("Searching...", "info")
+	pagesize := p.pageSize
+	offset := (p.currentPage - 1) * p.pageSize
+	loader.Load(p.app.loader, "search", func(ctx context.Context) (*api.SubjectList, error) {
+		return search.Search(p.client.WithContext(ctx), payload, pagesize, offset)
+	}, func(result *api.SubjectList, err error) {
+		if err != nil {
+			p.statusBar.SetMessage(fmt.Sprintf("Error searching: %v error", err.Error()), "error")
+			return
+		}
+		p.results = result.Data
+		p.totalResults = result.Total
+		if p.totalResults == 0 {
+			p.totalResults = len(result.Data)
+		}
+		maxPage := 1
+		if p.pageSize > 0 {
+			maxPage = (p.totalResults + p.pageSize - 1) / p.pageSize
+		}
+		statusMsg := fmt.Sprintf("Found %d results (Page %d/%d)", p.totalResults, p.currentPage, maxPage)
+		p.statusBar.SetMessage(statusMsg, "success")
+		p.render()
+	})
+}
+
+// payload builds the search request from the inputs. It is false if all inputs are empty.
+func (p *SearchPage) payload() (api.Payload, bool) {
 	filter := api.Filter{
 		MetaTags: []string{},
 		Tag:      []string{},
@@ -120,30 +140,17 @@ func (p *SearchPage) fetchData() {
 		filter.AirDate = append(filter.AirDate, endDate)
 	}
 	if keyword == "" && tags == "" && len(selectedTypes) == 0 && len(filter.AirDate) == 0 {
-		p.statusBar.SetMessage("Please enter a search query or tags", "warning")
-		return
+		return api.Payload{}, false
 	}
 	if tags != "" {
 		filter.Tag = strings.Split(tags, " ")
 	}
 	filter.Type = selectedTypes
-	payload := api.Payload{
+	return api.Payload{
 		Keyword: keyword,
 		Sort:    api.MATCH,
 		Filter:  filter,
-	}
-	pagesize := p.pageSize
-	offset := (p.currentPage - 1) * p.pageSize
-	result, err := search.Search(p.client, payload, pagesize, offset)
-	if err != nil {
-		p.statusBar.SetMessage(fmt.Sprintf("Error searching: %v error", err.Error()), "error")
-		return
-	}
-	p.results = result.Data
-	p.totalResults = result.Total
-	if p.totalResults == 0 {
-		p.totalResults = len(result.Data)
-	}
+	}, true
 }
 
 func (p *SearchPage) render() {
@@ -184,10 +191,10 @@ func (p *SearchPage) render() {
 	})
 
 	// Add headers
-	p.table.SetCell(0, 0, tview.NewTableCell("Title").SetTextColor(tcell.ColorYellow))
-	p.table.SetCell(0, 1, tview.NewTableCell("Type").SetTextColor(tcell.ColorYellow))
-	p.table.SetCell(0, 2, tview.NewTableCell("Tags").SetTextColor(tcell.ColorYellow))
-	p.table.SetCell(0, 3, tview.NewTableCell("Score").SetTextColor(tcell.ColorYellow))
+	p.table.SetCell(0, 0, tview.NewTableCell("Title").SetTextColor(ui.YellowColor()))
+	p.table.SetCell(0, 1, tview.NewTableCell("Type").SetTextColor(ui.YellowColor()))
+	p.table.SetCell(0, 2, tview.NewTableCell("Tags").SetTextColor(ui.YellowColor()))
+	p.table.SetCell(0, 3, tview.NewTableCell("Score").SetTextColor(ui.YellowColor()))
 
 	// Add results to table
 	for i, subject := range p.results {
@@ -278,46 +285,60 @@ func (p *SearchPage) setKeyBindings() {
 			return event
 		})
 	}
+	doubleClickToOpen(p.table, p.openSelected)
 	p.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Key() == tcell.KeyBacktab {
 			p.app.SetFocus(p.typeCheckboxes[len(p.typeCheckboxes)-1])
 			return nil
 		}
-		if event.Key() == tcell.KeyRune && event.Rune() == ' ' {
-			if cell := p.table.GetCell(p.table.GetSelection()); cell != nil && cell.GetReference() != nil {
-				if p.app != nil {
-					if subjectID, ok := cell.GetReference().(int); ok {
-						p.app.OpenSubjectPage(subjectID, "search")
-						return nil
-					}
-				}
+		switch p.app.keymap.Match(keymap.Search, event) {
+		case keymap.Open:
+			p.openSelected()
+			return nil
+		case keymap.NextPage:
+			maxPage := (p.totalResults + p.pageSize - 1) / p.pageSize
+			if p.currentPage < maxPage {
+				p.currentPage++
+				p.paginateSearch()
 			}
 			return nil
-		}
-		if event.Key() == tcell.KeyRune {
-			// Pagination: n for next, p for previous
-			switch event.Rune() {
-			case 'n':
-				maxPage := (p.totalResults + p.pageSize - 1) / p.pageSize
-				if p.currentPage < maxPage {
-					p.currentPage++
-					p.paginateSearch()
-				}
-				return nil
-			case 'p':
-				if p.currentPage > 1 {
-					p.currentPage--
-					p.paginateSearch()
-				}
-				return nil
-			default:
-				p.app.handlePageSwitch(event.Rune())
+		case keymap.PrevPage:
+			if p.currentPage > 1 {
+				p.currentPage--
+				p.paginateSearch()
 			}
+			return nil
+		}
+		if p.app.handleGlobalKey(event) {
+			return nil
 		}
 		return event
 	})
 }
 
+func (p *SearchPage) navState() navState {
+	row, column := p.table.GetSelection()
+	offset, _ := p.table.GetOffset()
+	return navState{Row: row, Column: column, Offset: offset}
+}
+
+// restoreNav selects the row of the state. Results are not saved between runs.
+func (p *SearchPage) restoreNav(state navState) {
+	if state.Row > 0 && state.Row < p.table.GetRowCount() {
+		p.table.Select(state.Row, state.Column)
+		p.table.SetOffset(state.Offset, 0)
+	}
+}
+
+// openSelected opens the subject of the selected row
+func (p *SearchPage) openSelected() {
+	if cell := p.table.GetCell(p.table.GetSelection()); cell != nil && cell.GetReference() != nil {
+		if subjectID, ok := cell.GetReference().(int); ok {
+			p.app.OpenSubjectPage(subjectID, "search")
+		}
+	}
+}
+
 // CheckedTypes assumes the checkboxes are ordered the same as api.S_TYPE_ALL
 // and returns selected subject types from indices.
 func (p *SearchPage) CheckedTypes() []api.SubjectType {
@@ -354,3 +375,11 @@ func (p *SearchPage) Focus(delegate func(tview.Primitive)) {
 		delegate(p.searchInput)
 	}
 }
+
+func (p *SearchPage) paletteSubjects() []paletteSubject {
+	subjects := make([]paletteSubject, len(p.results))
+	for i, s := range p.results {
+		subjects[i] = paletteSubject{ID: int(s.ID), Name: s.Name, NameCn: s.NameCn}
+	}
+	return subjects
+}
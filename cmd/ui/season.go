@@ -0,0 +1,289 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd/season"
+	"github.com/iucario/bangumi-go/cmd/subject"
+	"github.com/iucario/bangumi-go/internal/keymap"
+	"github.com/iucario/bangumi-go/internal/loader"
+	"github.com/iucario/bangumi-go/internal/ui"
+	"github.com/rivo/tview"
+)
+
+// SeasonPage lists the anime of a season grouped by platform
+type SeasonPage struct {
+	*tview.Grid
+	app      *App
+	season   season.Season
+	subjects []api.Subject // nil until loaded
+	sortBy   string        // heat or score
+	header   *tview.TextView
+	table    *tview.Table
+	// Statuses of subjects collected from this page
+	added map[int]api.CollectionStatus
+	// Subject to select when the season is loaded
+	restoreSubject int
+}
+
+// NewSeasonPage shows the current season. It is loaded when the page is first shown.
+func NewSeasonPage(a *App) *SeasonPage {
+	p := &SeasonPage{
+		Grid:   tview.NewGrid(),
+		app:    a,
+		season: season.Current(time.Now()),
+		sortBy: "heat",
+		header: tview.NewTextView().SetTextAlign(tview.AlignCenter),
+		table:  ui.NewTable().SetSelectable(true, false).SetFixed(1, 0),
+		added:  make(map[int]api.CollectionStatus),
+	}
+	p.header.SetTextColor(ui.Styles.TitleColor)
+	footer := tview.NewTextView().SetDynamicColors(true).SetTextAlign(tview.AlignCenter).
+		SetText("Space/Enter: 详情  n/p: 下一季/上一季  o: 排序  w: 想看  R: 刷新  ?: Help")
+	p.SetRows(1, 0, 1).SetColumns(0)
+	p.AddItem(p.header, 0, 0, 1, 1, 0, 0, false).
+		AddItem(p.table, 1, 0, 1, 1, 0, 0, true).
+		AddItem(footer, 2, 0, 1, 1, 0, 0, false)
+	p.render()
+	p.setKeyBindings()
+	return p
+}
+
+func (p *SeasonPage) GetName() string {
+	return "season"
+}
+
+// Focus loads the season the first time the page is shown
+func (p *SeasonPage) Focus(delegate func(tview.Primitive)) {
+	if p.subjects == nil && !p.app.loader.Loading("season") {
+		p.Refresh()
+	}
+	delegate(p.table)
+}
+
+// Refresh loads all anime of the season in the background
+func (p *SeasonPage) Refresh() {
+	s := p.season
+	loader.Load(p.app.loader, "season", func(ctx context.Context) ([]api.Subject, error) {
+		return season.Fetch(p.app.User.Client.WithContext(ctx), s)
+	}, func(subjects []api.Subject, err error) {
+		if err != nil {
+			p.render()
+			return
+		}
+		if subjects == nil {
+			subjects = []api.Subject{}
+		}
+		p.subjects = subjects
+		if p.restoreSubject == 0 {
+			p.restoreSubject = p.selectedID()
+		}
+		p.render()
+	})
+	p.render()
+}
+
+// switchSeason shows the season n seasons later, or earlier if n is negative
+func (p *SeasonPage) switchSeason(n int) {
+	p.season = p.season.Add(n)
+	p.subjects = nil
+	p.table.Select(1, 0)
+	p.Refresh()
+}
+
+func (p *SeasonPage) render() {
+	title := fmt.Sprintf("%s · by %s", p.season, p.sortBy)
+	if p.app.loader.Loading("season") {
+		title += " (loading...)"
+	} else if p.subjects != nil {
+		title = fmt.Sprintf("%s · %d · by %s", p.season, len(p.subjects), p.sortBy)
+	}
+	p.header.SetText(title)
+
+	p.table.Clear()
+	for col, name := range []string{"Title", "Score", "Heat", "Date", "Status"} {
+		p.table.SetCell(0, col, tview.NewTableCell(name).SetTextColor(ui.YellowColor()).SetSelectable(false))
+	}
+	subjects := append([]api.Subject(nil), p.subjects...)
+	season.SortSubjects(subjects, p.sortBy)
+	row := 1
+	for _, group := range season.GroupByPlatform(subjects) {
+		p.table.SetCell(row, 0, tview.NewTableCell(fmt.Sprintf("%s (%d)", group.Platform, len(group.Subjects))).
+			SetTextColor(ui.Styles.TitleColor).SetAttributes(tcell.AttrBold).SetSelectable(false))
+		row++
+		for _, s := range group.Subjects {
+			p.table.SetCell(row, 0, tview.NewTableCell(tview.Escape(s.GetName())).SetReference(int(s.ID)).SetExpansion(1))
+			p.table.SetCell(row, 1, tview.NewTableCell(fmt.Sprintf("%.1f", s.Rating.Score)).SetAlign(tview.AlignRight))
+			p.table.SetCell(row, 2, tview.NewTableCell(fmt.Sprintf("%d", season.Heat(&s))).SetAlign(tview.AlignRight))
+			p.table.SetCell(row, 3, tview.NewTableCell(s.Date).SetTextColor(ui.GreyColor()))
+			status := tview.NewTableCell("")
+			if collected, ok := p.status(int(s.ID)); ok {
+				status.SetText(string(collected)).SetTextColor(ui.CyanColor())
+			}
+			p.table.SetCell(row, 4, status)
+			row++
+		}
+	}
+	if p.restoreSubject != 0 && p.subjects != nil {
+		p.selectSubject(p.restoreSubject)
+		p.restoreSubject = 0
+	}
+	if selected, _ := p.table.GetSelection(); selected < 1 || selected >= p.table.GetRowCount() || p.selectedID() == 0 {
+		p.table.Select(2, 0)
+	}
+}
+
+// status returns the collection status of a subject if it is known from this page or the loaded lists
+func (p *SeasonPage) status(id int) (api.CollectionStatus, bool) {
+	if status, ok := p.added[id]; ok {
+		return status, true
+	}
+	for _, status := range api.C_STATUS {
+		if page, ok := p.app.pages[status.String()].(*CollectionPage); ok {
+			if indexOfCollection(page.Collections, uint32(id)) >= 0 {
+				return status, true
+			}
+		}
+	}
+	return "", false
+}
+
+// selectedID returns the subject ID of the selected row, 0 if none
+func (p *SeasonPage) selectedID() int {
+	row, _ := p.table.GetSelection()
+	id, _ := p.table.GetCell(row, 0).GetReference().(int)
+	return id
+}
+
+// selectSubject selects the row of a subject. It reports false if the subject is not listed.
+func (p *SeasonPage) selectSubject(id int) bool {
+	for row := range p.table.GetRowCount() {
+		if ref, ok := p.table.GetCell(row, 0).GetReference().(int); ok && ref == id {
+			p.table.Select(row, 0)
+			return true
+		}
+	}
+	return false
+}
+
+// addWish adds the selected subject to the wish list unless it is already collected
+func (p *SeasonPage) addWish() {
+	id := p.selectedID()
+	if id == 0 {
+		return
+	}
+	if p.app.User.Username == "" {
+		p.app.NotifyWithStyle("Login to collect subjects", "warning")
+		return
+	}
+	if status, ok := p.status(id); ok {
+		p.app.NotifyWithStyle(fmt.Sprintf("Already in %s", status), "warning")
+		return
+	}
+	name := p.table.GetCell(p.table.GetSelection()).Text
+	username := p.app.User.Username
+	loader.Load(p.app.loader, fmt.Sprintf("season.wish %d", id), func(ctx context.Context) (api.CollectionStatus, error) {
+		client := p.app.User.Client.WithContext(ctx)
+		// Lists may not be fully loaded, so ask before overwriting a collection
+		collection, err := subject.GetUserSubjectCollection(client, username, id)
+		var requestErr *api.RequestError
+		if err == nil && collection.Type != 0 {
+			return collection.GetStatus(), nil
+		}
+		if err != nil && (!errors.As(err, &requestErr) || requestErr.StatusCode != http.StatusNotFound) {
+			return "", err
+		}
+		return api.Wish, subject.PostCollection(client, id, api.Wish, nil, "", 0, false)
+	}, func(status api.CollectionStatus, err error) {
+		if err != nil {
+			return
+		}
+		p.added[id] = status
+		p.restoreSubject = id
+		p.render()
+		if status != api.Wish {
+			p.app.NotifyWithStyle(fmt.Sprintf("%s is already in %s", name, status), "warning")
+			return
+		}
+		p.app.NotifyWithStyle(fmt.Sprintf("Added %s to wish", name), "success")
+		if page, ok := p.app.pages[string(api.Wish)].(*CollectionPage); ok {
+			page.Refresh()
+		}
+	})
+}
+
+// openSelected opens the subject of the selected row
+func (p *SeasonPage) openSelected() {
+	if id := p.selectedID(); id != 0 {
+		p.app.OpenSubjectPage(id, "season")
+	}
+}
+
+func (p *SeasonPage) setKeyBindings() {
+	p.table.SetSelectedFunc(func(row, column int) {
+		p.openSelected()
+	})
+	doubleClickToOpen(p.table, p.openSelected)
+	p.table.SetInputCapture(p.app.handleScrollKeys(p.table))
+	p.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch p.app.keymap.Match(keymap.Season, event) {
+		case keymap.Open:
+			p.openSelected()
+		case keymap.NextPage:
+			p.switchSeason(1)
+		case keymap.PrevPage:
+			p.switchSeason(-1)
+		case keymap.Sort:
+			p.restoreSubject = p.selectedID()
+			if p.sortBy == "heat" {
+				p.sortBy = "score"
+			} else {
+				p.sortBy = "heat"
+			}
+			p.render()
+		case keymap.AddWish:
+			p.addWish()
+		case keymap.Refresh:
+			p.restoreSubject = p.selectedID()
+			p.Refresh()
+		default:
+			if p.app.handleGlobalKey(event) {
+				return nil
+			}
+			return event
+		}
+		return nil
+	})
+}
+
+func (p *SeasonPage) navState() navState {
+	row, _ := p.table.GetSelection()
+	offset, _ := p.table.GetOffset()
+	return navState{SubjectID: p.selectedID(), Row: row, Offset: offset}
+}
+
+// restoreNav selects the subject of the state now, or when the season is loaded
+func (p *SeasonPage) restoreNav(state navState) {
+	if p.subjects == nil {
+		p.restoreSubject = state.SubjectID
+		return
+	}
+	if !p.selectSubject(state.SubjectID) && state.Row > 0 && state.Row < p.table.GetRowCount() {
+		p.table.Select(state.Row, 0)
+	}
+	p.table.SetOffset(state.Offset, 0)
+}
+
+func (p *SeasonPage) paletteSubjects() []paletteSubject {
+	subjects := make([]paletteSubject, len(p.subjects))
+	for i, s := range p.subjects {
+		subjects[i] = paletteSubject{ID: int(s.ID), Name: s.Name, NameCn: s.NameCn}
+	}
+	return subjects
+}
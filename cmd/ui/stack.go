@@ -0,0 +1,93 @@
+package tui
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/internal/config"
+)
+
+// maxSubjectPages limits the subject pages kept open for going back and forward
+const maxSubjectPages = 10
+
+// maxRecent limits the recently viewed subjects
+const maxRecent = 30
+
+// subjectPage returns the open page of a subject, or creates it.
+// Open pages keep their data, so going back to a subject does not load it again.
+// The page becomes the last of the stack and the oldest page is closed if there are too many.
+func (a *App) subjectPage(id int) *SubjectPage {
+	index := slices.IndexFunc(a.subjects, func(page *SubjectPage) bool { return page.ID == id })
+	if index >= 0 {
+		page := a.subjects[index]
+		a.subjects = append(slices.Delete(a.subjects, index, index+1), page)
+		// Loading failed before
+		if page.Subject == nil && !a.loader.Loading(page.loadKey()) {
+			page.Refresh()
+		}
+		return page
+	}
+	page := NewSubjectPage(a, id)
+	a.subjects = append(a.subjects, page)
+	if len(a.subjects) > maxSubjectPages {
+		a.subjects = slices.Delete(a.subjects, 0, len(a.subjects)-maxSubjectPages)
+	}
+	return page
+}
+
+// visibleSubject returns the subject page on the screen, either the subject page
+// or a subject beside a collection list. nil if none.
+func (a *App) visibleSubject() *SubjectPage {
+	switch page := a.pages[a.currentPage].(type) {
+	case *SubjectPage:
+		return page
+	case *CollectionPage:
+		return page.subjectView
+	}
+	return nil
+}
+
+// loadRecent returns the recently viewed subjects, the latest first
+func loadRecent() []paletteSubject {
+	var recent []paletteSubject
+	if err := config.LoadState("recent", &recent); err != nil {
+		slog.Error("Failed to load recent subjects", "error", err)
+	}
+	return recent
+}
+
+// addRecent moves a subject to the top of the recently viewed subjects
+func (a *App) addRecent(sbj *api.Subject) {
+	recent := slices.DeleteFunc(loadRecent(), func(s paletteSubject) bool { return s.ID == int(sbj.ID) })
+	recent = slices.Insert(recent, 0, paletteSubject{ID: int(sbj.ID), Name: sbj.Name, NameCn: sbj.NameCn})
+	recent = recent[:min(len(recent), maxRecent)]
+	if err := config.SaveState("recent", recent); err != nil {
+		slog.Error("Failed to save recent subjects", "error", err)
+	}
+}
+
+// recentItems opens the recently viewed subjects from the palette
+func (a *App) recentItems() []PaletteItem {
+	prevPage := a.currentPage
+	var items []PaletteItem
+	for _, s := range loadRecent() {
+		items = append(items, PaletteItem{
+			Label:  s.label(),
+			Detail: fmt.Sprintf("subject %d", s.ID),
+			Run:    func() { a.OpenSubjectPage(s.ID, prevPage) },
+		})
+	}
+	return items
+}
+
+// OpenRecent lists the recently viewed subjects to open one
+func (a *App) OpenRecent() {
+	items := a.recentItems()
+	if len(items) == 0 {
+		a.Notify("No recently viewed subjects")
+		return
+	}
+	a.openPalette("Recently viewed", items)
+}
@@ -0,0 +1,142 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd/list"
+	"github.com/iucario/bangumi-go/cmd/stats"
+	"github.com/iucario/bangumi-go/internal/keymap"
+	"github.com/iucario/bangumi-go/internal/loader"
+	"github.com/iucario/bangumi-go/internal/ui"
+	"github.com/rivo/tview"
+)
+
+const statsBarWidth = 30
+
+// StatsPage shows distributions over the whole collection with text bar charts.
+type StatsPage struct {
+	*tview.Grid
+	app   *App
+	stats *stats.Stats
+	view  *tview.TextView
+}
+
+func NewStatsPage(app *App) *StatsPage {
+	page := &StatsPage{
+		Grid: tview.NewGrid(),
+		app:  app,
+		view: tview.NewTextView().SetDynamicColors(true).SetScrollable(true).SetWrap(false),
+	}
+	page.render()
+	page.setKeyBindings()
+	page.fetchData()
+	return page
+}
+
+func (p *StatsPage) GetName() string {
+	return "stats"
+}
+
+// fetchData loads all collections in the background.
+// Watch time is estimated to avoid fetching episodes of every subject.
+func (p *StatsPage) fetchData() {
+	options := list.UserListOptions{
+		Username:       p.app.User.Username,
+		SubjectType:    "all",
+		CollectionType: api.All,
+	}
+	loader.Load(p.app.loader, "stats", func(ctx context.Context) ([]api.UserSubjectCollection, error) {
+		return list.ListAllUserCollection(p.app.User.Client.WithContext(ctx), options)
+	}, func(collections []api.UserSubjectCollection, err error) {
+		if err == nil {
+			p.stats = stats.Compute(collections, nil, 0)
+		}
+		p.view.SetText(p.createText())
+	})
+	p.view.SetText(p.createText())
+}
+
+func (p *StatsPage) render() {
+	p.SetRows(1, -1, 1)
+	p.SetColumns(-1)
+	header := tview.NewTextView().
+		SetText("收藏统计").
+		SetTextAlign(tview.AlignCenter).
+		SetTextColor(ui.Styles.TitleColor)
+	footer := tview.NewTextView().SetDynamicColors(true).SetTextAlign(tview.AlignCenter)
+	footer.SetText("R: 刷新  ↑/↓: 滚动  ?: Help")
+	p.view.SetText(p.createText())
+
+	p.Clear()
+	p.AddItem(header, 0, 0, 1, 1, 0, 0, false).
+		AddItem(p.view, 1, 0, 1, 1, 0, 0, true).
+		AddItem(footer, 2, 0, 1, 1, 0, 0, false)
+}
+
+func (p *StatsPage) Refresh() {
+	p.fetchData()
+}
+
+func (p *StatsPage) createText() string {
+	s := p.stats
+	if s == nil {
+		if p.app.loader.Loading("stats") {
+			return "加载中..."
+		}
+		return "No data"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n总收藏: %s\n\n", ui.SecondaryText(fmt.Sprintf("%d", s.Total)))
+
+	b.WriteString(ui.TertiaryText("状态") + "\n")
+	statusBars := stats.Bars(statsBarWidth, s.StatusCounts())[0]
+	for i, status := range api.C_STATUS {
+		fmt.Fprintf(&b, "%-9s %5d %s\n", status, s.ByStatus[status], ui.Blue(statusBars[i]))
+	}
+
+	b.WriteString("\n" + ui.TertiaryText("类型") + "\n")
+	typeBars := stats.Bars(statsBarWidth, s.TypeCounts())[0]
+	for i, t := range api.S_TYPE_ALL {
+		fmt.Fprintf(&b, "%-9s %5d %s\n", t, s.ByType[t], ui.Blue(typeBars[i]))
+	}
+
+	fmt.Fprintf(&b, "\n%s  %s %.2f  %s %.2f\n", ui.TertiaryText("评分"),
+		ui.Purple("我的"), s.MeanRate, ui.Cyan("社区"), s.MeanScore)
+	mine, community := s.RateBars(statsBarWidth)
+	for i := 10; i >= 1; i-- {
+		fmt.Fprintf(&b, "%2d %4d %s%s %4d %s\n", i, s.RateHistogram[i], ui.Purple(mine[i]),
+			strings.Repeat(" ", statsBarWidth-len([]rune(mine[i]))), s.ScoreHistogram[i], ui.Cyan(community[i]))
+	}
+
+	b.WriteString("\n" + ui.TertiaryText("我的标签") + "\n")
+	for _, tag := range s.TopTags {
+		fmt.Fprintf(&b, "%s(%d) ", ui.SecondaryText(tview.Escape(tag.Name)), tag.Count)
+	}
+	b.WriteString("\n" + ui.TertiaryText("用户标签") + "\n")
+	for _, tag := range s.TopSubjectTags {
+		fmt.Fprintf(&b, "%s(%d) ", tview.Escape(tag.Name), tag.Count)
+	}
+	b.WriteString("\n\n")
+
+	fmt.Fprintf(&b, "完成率: %s\n", ui.SecondaryText(fmt.Sprintf("%.1f%%", s.CompletionRate*100)))
+	fmt.Fprintf(&b, "看过集数: %s (每周 %.1f 集)\n", ui.SecondaryText(fmt.Sprintf("%d", s.Episodes)), s.EpisodesPerWeek)
+	fmt.Fprintf(&b, "观看时长: %s (估算)\n", ui.SecondaryText(fmt.Sprintf("%.1f 小时", s.Hours())))
+	return b.String()
+}
+
+func (p *StatsPage) setKeyBindings() {
+	p.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if p.app.keymap.Match(keymap.Stats, event) == keymap.Refresh {
+			p.Refresh()
+			return nil
+		}
+		if p.app.handleGlobalKey(event) {
+			return nil
+		}
+		return event
+	})
+}
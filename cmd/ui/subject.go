@@ -1,8 +1,10 @@
 package tui
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"image"
 	"log/slog"
 	"strings"
 	"time"
@@ -10,6 +12,9 @@ import (
 	"github.com/gdamore/tcell/v2"
 	"github.com/iucario/bangumi-go/api"
 	"github.com/iucario/bangumi-go/cmd/subject"
+	"github.com/iucario/bangumi-go/internal/cover"
+	"github.com/iucario/bangumi-go/internal/keymap"
+	"github.com/iucario/bangumi-go/internal/loader"
 	"github.com/iucario/bangumi-go/internal/task"
 	"github.com/iucario/bangumi-go/internal/ui"
 	"github.com/rivo/tview"
@@ -19,79 +24,130 @@ type SubjectPage struct {
 	*tview.Grid
 	client       api.Client
 	app          *App
-	Subject      *api.Subject
+	ID           int
+	Subject      *api.Subject // nil until loaded
 	Episodes     *api.Episodes
+	Related      []api.RelatedSubject
+	header       *tview.TextView
+	coverView    *ui.ImageView
 	leftContent  *tview.TextView
 	rightContent *tview.TextView
+	right        *tview.Flex
+	split        *ui.SplitPane
+	episodeGrid  *tview.Table
+	relatedList  *tview.List
 	// Optional
 	Collection *api.UserSubjectCollection
+	// Episode collection types of the user by episode ID. See api.EpisodeCollectionType.
+	EpisodeStatus map[int]int
+	restoreOffset int // Scroll of the summary to restore when loaded
 }
 
+// subjectData is everything a subject page fetches
+type subjectData struct {
+	subject       *api.Subject
+	collection    *api.UserSubjectCollection
+	episodes      *api.Episodes
+	episodeStatus map[int]int
+	related       []api.RelatedSubject
+}
+
+// NewSubjectPage shows a placeholder immediately and loads the subject in the background.
 func NewSubjectPage(a *App, ID int) *SubjectPage {
-	// Get data concurrently
+	sub := &SubjectPage{
+		Grid:   tview.NewGrid(),
+		app:    a,
+		ID:     ID,
+		client: a.User.Client,
+	}
+	sub.render()
+	sub.setKeyBindings()
+	sub.Refresh()
+	return sub
+}
+
+// fetchSubject gets subject, episodes and user collection concurrently.
+// Only a failure of the subject itself is an error.
+func (s *SubjectPage) fetchSubject(ctx context.Context) (*subjectData, error) {
+	client := s.app.User.Client.WithContext(ctx)
 	tasks := []task.Task{
 		{
 			ID: "subject",
 			Do: func() (any, error) {
-				return subject.GetSubjectInfo(a.User.Client, ID)
+				return subject.GetSubjectInfo(client, s.ID)
 			},
 		},
 		{
 			ID: "collection",
 			Do: func() (any, error) {
-				return subject.GetUserSubjectCollection(a.User.Client, a.User.Username, ID)
+				return subject.GetUserSubjectCollection(client, s.app.User.Username, s.ID)
 			},
 		},
 		{
 			ID: "episodes",
 			Do: func() (any, error) {
-				return subject.GetEpisodes(a.User.Client.HTTPClient, ID, 0, 100)
+				return subject.GetEpisodes(client.HTTPClient, s.ID, 0, 100)
+			},
+		},
+		{
+			ID: "episodeStatus",
+			Do: func() (any, error) {
+				return subject.GetUserEpisodeCollections(client, s.ID, 0, 100, api.EpisodeType["DEFAULT"])
+			},
+		},
+		{
+			ID: "related",
+			Do: func() (any, error) {
+				return subject.GetRelatedSubjects(client, s.ID)
 			},
 		},
 	}
 	res := task.Run(tasks)
 
-	// TODO: fix this ugly block
-	sbjRes, ok := res["subject"]
-	if !ok || sbjRes.Error != nil || sbjRes.Data == nil {
-		slog.Error("Failed to fetch subject info", "ID", ID, "Error", sbjRes.Error)
-		return nil
+	if err := res["subject"].Error; err != nil {
+		return nil, err
 	}
-	sbj := sbjRes.Data.(*api.Subject)
+	sbj, ok := res["subject"].Data.(*api.Subject)
+	if !ok || sbj == nil {
+		return nil, fmt.Errorf("subject %d not found", s.ID)
+	}
+	data := &subjectData{subject: sbj, episodeStatus: make(map[int]int)}
 
-	episodesRes, ok := res["episodes"]
-	if !ok || episodesRes.Error != nil || episodesRes.Data == nil {
-		slog.Error("Failed to fetch episodes", "Error", episodesRes.Error)
+	if episodes, ok := res["episodes"].Data.(*api.Episodes); ok && res["episodes"].Error == nil {
+		data.episodes = episodes
+	} else {
+		slog.Error("Failed to fetch episodes", "Error", res["episodes"].Error)
+	}
+
+	if related, ok := res["related"].Data.([]api.RelatedSubject); ok && res["related"].Error == nil {
+		data.related = related
+	} else {
+		slog.Error("Failed to fetch related subjects", "Error", res["related"].Error)
+	}
+
+	// Not collected subjects have no episode status
+	if userEpisodes, ok := res["episodeStatus"].Data.(api.UserEpisodeCollections); ok && res["episodeStatus"].Error == nil {
+		for _, e := range userEpisodes.Data {
+			data.episodeStatus[e.Episode.ID] = e.Type
+		}
 	}
-	episodes := episodesRes.Data.(*api.Episodes)
 
 	// Get user collection data for this subject
-	collection := &api.UserSubjectCollection{
+	data.collection = &api.UserSubjectCollection{
 		SubjectType: sbj.Type,
 		Subject:     sbj.SlimSubject,
 		Type:        0,
 		SubjectID:   sbj.ID,
 	}
-	if a.User != nil && a.User.Client != nil && a.User.Username != "" {
+	if s.app.User.Username != "" {
 		c, ok := res["collection"].Data.(api.UserSubjectCollection)
 		if ok && c.Type != 0 {
-			collection = &c
+			data.collection = &c
 		} else {
 			slog.Error("Failed to fetch collection", "Error", res["collection"].Error)
 		}
 	}
-
-	sub := &SubjectPage{
-		Grid:       tview.NewGrid(),
-		app:        a,
-		Subject:    sbj,
-		Episodes:   episodes,
-		client:     a.User.Client,
-		Collection: collection,
-	}
-	sub.render()
-	sub.setKeyBindings()
-	return sub
+	return data, nil
 }
 
 func (s *SubjectPage) GetName() string {
@@ -103,83 +159,223 @@ func (s *SubjectPage) GetName() string {
 // Content has two parts, left and right content
 func (s *SubjectPage) render() {
 	s.SetRows(1, 0, 1)
-	s.SetColumns(40, -1)
+	s.SetColumns(-1)
 	s.SetBorder(false)
 	s.SetBorders(false)
-	s.SetBorderColor(tcell.ColorGray)
-	top := tview.NewTextView().SetTextAlign(tview.AlignCenter)
-	top.SetText(fmt.Sprintf("%s %s %s", s.Subject.GetName(), s.Subject.Platform, api.SubjectTypeRev[int(s.Subject.Type)]))
-	top.SetTextColor(ui.Styles.TitleColor)
-
-	text := s.createLeftText()
-	rightText := s.createRightText()
+	s.SetBorderColor(ui.Styles.BorderColor)
+	s.header = tview.NewTextView().SetTextAlign(tview.AlignCenter)
+	s.header.SetTextColor(ui.Styles.TitleColor)
+	s.coverView = ui.NewImageView(s.app.imageProtocol).SetText("加载中...")
 	s.leftContent = tview.NewTextView().SetDynamicColors(true).SetScrollable(true).SetWrap(true)
 	s.rightContent = tview.NewTextView().SetDynamicColors(true).SetScrollable(true).SetWrap(true)
-	s.leftContent.SetText(text)
-	s.rightContent.SetText(rightText)
+	s.episodeGrid = ui.NewTable().SetSelectable(true, true)
+	s.relatedList = ui.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+	s.relatedList.SetBorder(true).SetTitle("关联").SetTitleAlign(tview.AlignLeft)
+	s.relatedList.SetBorderColor(ui.Styles.BorderColor)
+	s.relatedList.SetSelectedFunc(func(index int, mainText, secondaryText string, shortcut rune) {
+		s.openRelated(index)
+	})
+	s.relatedList.SetInputCapture(s.app.handleScrollKeys(s.relatedList))
+	s.app.clickToSelect(s.relatedList, 1, s.openRelated)
+	s.right = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(s.rightContent, 0, 1, true).
+		AddItem(s.episodeGrid, 3, 0, false)
+	s.renderContent()
 	// Initially, leftContent is focused, so show its border
 	s.leftContent.SetBorder(true)
 	s.rightContent.SetBorder(true)
+	s.episodeGrid.SetBorder(true).SetTitleAlign(tview.AlignLeft)
+	s.episodeGrid.SetBorderColor(ui.Styles.BorderColor)
+	s.episodeGrid.SetSelectionChangedFunc(func(row, column int) {
+		s.episodeGrid.SetTitle(s.episodeTitle(row, column))
+	})
+	s.episodeGrid.SetInputCapture(s.handleEpisodeKeys)
+	s.leftContent.SetInputCapture(s.app.handleScrollKeys(s.leftContent))
+	s.rightContent.SetInputCapture(s.app.handleScrollKeys(s.rightContent))
 
 	// Change border color on focus/blur
 	s.leftContent.SetFocusFunc(func() {
 		s.leftContent.SetBorderColor(ui.Styles.TitleColor) // Focused color
 	})
 	s.leftContent.SetBlurFunc(func() {
-		s.leftContent.SetBorderColor(tcell.ColorGray) // Unfocused color
+		s.leftContent.SetBorderColor(ui.Styles.BorderColor) // Unfocused color
 	})
 	s.rightContent.SetFocusFunc(func() {
 		s.rightContent.SetBorderColor(ui.Styles.TitleColor)
 	})
 	s.rightContent.SetBlurFunc(func() {
-		s.rightContent.SetBorderColor(tcell.ColorGray)
+		s.rightContent.SetBorderColor(ui.Styles.BorderColor)
+	})
+	s.episodeGrid.SetFocusFunc(func() {
+		s.episodeGrid.SetBorderColor(ui.Styles.TitleColor)
+	})
+	s.episodeGrid.SetBlurFunc(func() {
+		s.episodeGrid.SetBorderColor(ui.Styles.BorderColor)
+	})
+	s.relatedList.SetFocusFunc(func() {
+		s.relatedList.SetBorderColor(ui.Styles.TitleColor)
+	})
+	s.relatedList.SetBlurFunc(func() {
+		s.relatedList.SetBorderColor(ui.Styles.BorderColor)
 	})
 
 	footer := tview.NewTextView().SetDynamicColors(true).SetTextAlign(tview.AlignCenter)
-	footer.SetText("e: 编辑  q: 返回  R: 刷新  ←/→: 移动  ↑/↓: 滚动  ?: Help")
+	footer.SetText("e: 编辑  q: 返回  R: 刷新  Tab: 切换  z: 最大化  Space/d/w/x: 标记剧集  ?: Help")
 
-	s.AddItem(top, 0, 0, 1, 2, 0, 0, false).
-		AddItem(s.leftContent, 1, 0, 1, 1, 0, 0, false).
-		AddItem(s.rightContent, 1, 1, 1, 1, 0, 0, true).
-		AddItem(footer, 2, 0, 1, 2, 0, 0, false)
+	s.split = s.app.newSplit("subject", s.left(), s.right)
+	s.AddItem(s.header, 0, 0, 1, 1, 0, 0, false).
+		AddItem(s.split, 1, 0, 1, 1, 0, 0, true).
+		AddItem(footer, 2, 0, 1, 1, 0, 0, false)
 }
 
-func (s *SubjectPage) Refresh() {
-	slog.Debug("subject refresh")
-	s.Subject, _ = subject.GetSubjectInfo(s.client, int(s.Subject.ID))
-	if s.app.User != nil && s.app.User.Client != nil && s.app.User.Username != "" {
-		c, err := subject.GetUserSubjectCollection(s.app.User.Client, s.app.User.Username, int(s.Subject.ID))
-		if err == nil && c.Type != 0 {
-			s.Collection = &c
-		} else {
-			s.Collection = nil
+// left has the cover above the text and related subjects below it
+func (s *SubjectPage) left() tview.Primitive {
+	left := tview.NewFlex().SetDirection(tview.FlexRow)
+	if s.app.imageProtocol != cover.None {
+		left.AddItem(s.coverView, coverHeight, 0, false)
+	}
+	return left.AddItem(s.leftContent, 0, 1, true).
+		AddItem(s.relatedList, relatedHeight, 0, false)
+}
+
+// relatedHeight is the height of the related subjects list in rows
+const relatedHeight = 8
+
+// coverHeight is the height of the cover in rows
+const coverHeight = 14
+
+// loadCover shows the cover of the subject when it is downloaded or read from the cache
+func (s *SubjectPage) loadCover() {
+	url := cover.URL(s.Subject.Images)
+	if url == "" || s.app.imageProtocol == cover.None {
+		s.coverView.SetImage(nil).SetText("无封面")
+		return
+	}
+	fetch := func(ctx context.Context) (image.Image, error) {
+		return cover.Load(ctx, url)
+	}
+	loader.Load(s.app.loader, fmt.Sprintf("cover %d", s.ID), fetch, func(img image.Image, err error) {
+		if err != nil {
+			s.coverView.SetImage(nil).SetText("无封面")
+			return
 		}
+		s.coverView.SetImage(img)
+	})
+}
+
+// renderContent fills header and both panes. A skeleton is shown before the subject is loaded.
+func (s *SubjectPage) renderContent() {
+	if s.Subject == nil {
+		s.header.SetText(fmt.Sprintf("Subject %d", s.ID))
+		s.leftContent.SetText(ui.Grey("加载中...\n\n集数: -\n评分: -\n排名: -"))
+		s.rightContent.SetText(ui.Grey("加载中..."))
+		s.episodeGrid.SetTitle("正片")
+		s.relatedList.Clear()
+		return
 	}
+	s.header.SetText(fmt.Sprintf("%s %s %s", s.Subject.GetName(), s.Subject.Platform, api.SubjectTypeRev[int(s.Subject.Type)]))
 	s.leftContent.SetText(s.createLeftText())
 	s.rightContent.SetText(s.createRightText())
+	s.renderEpisodes()
+	s.renderRelated()
+}
+
+func (s *SubjectPage) renderRelated() {
+	s.relatedList.Clear()
+	for _, r := range s.Related {
+		s.relatedList.AddItem(fmt.Sprintf("%s %s", ui.Grey(tview.Escape(r.Relation)), tview.Escape(r.GetName())), "", 0, nil)
+	}
+	if len(s.Related) == 0 {
+		s.relatedList.AddItem(ui.Grey("无"), "", 0, nil)
+	}
+}
+
+// openRelated opens a related subject. Going back returns to this subject.
+func (s *SubjectPage) openRelated(index int) {
+	if index < 0 || index >= len(s.Related) {
+		return
+	}
+	s.app.OpenSubjectPage(int(s.Related[index].ID), s.app.currentPage)
+}
+
+// loadKey names the load of this subject. Each page has its own, so opening another
+// subject does not cancel it.
+func (s *SubjectPage) loadKey() string {
+	return fmt.Sprintf("subject %d", s.ID)
+}
+
+// Refresh loads the subject in the background. Refreshing again cancels the previous load.
+func (s *SubjectPage) Refresh() {
+	slog.Debug("subject refresh")
+	loader.Load(s.app.loader, s.loadKey(), s.fetchSubject, func(data *subjectData, err error) {
+		if err != nil {
+			if s.Subject == nil {
+				s.leftContent.SetText(ui.Red("加载失败, R: 重试"))
+				s.rightContent.SetText("")
+			}
+			return
+		}
+		s.Subject = data.subject
+		s.Episodes = data.episodes
+		s.Collection = data.collection
+		s.EpisodeStatus = data.episodeStatus
+		s.Related = data.related
+		s.renderContent()
+		s.rightContent.ScrollTo(s.restoreOffset, 0)
+		s.restoreOffset = 0
+		s.loadCover()
+		s.app.addRecent(s.Subject)
+		s.app.tabs.Update()
+	})
+}
+
+func (s *SubjectPage) navState() navState {
+	offset, _ := s.rightContent.GetScrollOffset()
+	return navState{SubjectID: s.ID, Offset: offset}
+}
+
+// restoreNav scrolls the summary now, or when the subject is loaded
+func (s *SubjectPage) restoreNav(state navState) {
+	if s.Subject == nil {
+		s.restoreOffset = state.Offset
+		return
+	}
+	s.rightContent.ScrollTo(state.Offset, 0)
 }
 
 func (s *SubjectPage) setKeyBindings() {
 	s.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyLeft:
-			s.app.SetFocus(s.leftContent)
-		case tcell.KeyRight:
-			s.app.SetFocus(s.rightContent)
-		case tcell.KeyRune:
-			switch event.Rune() {
-			case 'e':
-				modal := NewCollectModal(s.app, s.Collection, s.onSave)
-				if modal != nil {
-					s.app.Pages.AddPage("collect", modal, true, true)
-					s.app.SetFocus(modal)
-				} else {
-					s.app.NotifyWithStyle("collection is nil", "error")
-				}
-			case 'R':
-				s.Refresh()
-			default:
-				s.app.handlePageSwitch(event.Rune())
+		switch s.app.keymap.Match(keymap.Subject, event) {
+		case keymap.FocusLeft:
+			if !s.episodeGrid.HasFocus() {
+				s.app.SetFocus(s.leftContent)
+			}
+		case keymap.FocusRight:
+			if !s.episodeGrid.HasFocus() {
+				s.app.SetFocus(s.rightContent)
+			}
+		case keymap.FocusNext:
+			s.cycleFocus()
+			return nil
+		case keymap.Maximize:
+			s.split.ToggleMaximize()
+		case keymap.Edit:
+			if s.Subject == nil {
+				s.app.NotifyWithStyle("Subject is still loading", "warning")
+				return event
+			}
+			modal := NewCollectModal(s.app, s.Collection, s.onSave)
+			if modal != nil {
+				s.app.Pages.AddPage("collect", modal, true, true)
+				s.app.SetFocus(modal)
+			} else {
+				s.app.NotifyWithStyle("collection is nil", "error")
+			}
+		case keymap.Refresh:
+			s.Refresh()
+		default:
+			if s.app.handleGlobalKey(event) {
+				return nil
 			}
 		}
 		return event
@@ -218,7 +414,9 @@ func (s *SubjectPage) onSave(collection *api.UserSubjectCollection) error {
 	}
 
 	if EpisodeStatusChanged(original, collection) {
-		subject.WatchToEpisode(s.app.User.Client, int(s.Subject.ID), int(collection.EpStatus))
+		if err := subject.WatchToEpisode(s.app.User.Client, int(s.Subject.ID), int(collection.EpStatus)); err != nil {
+			return err
+		}
 	}
 
 	// Update collection info
@@ -250,6 +448,7 @@ func (s *SubjectPage) createLeftText() string {
 	text += fmt.Sprintf("评分: %.1f\n", s.Subject.Rating.Score)
 	text += fmt.Sprintf("排名: %d\n", s.Subject.Rating.Rank)
 	text += fmt.Sprintf("评分人数: %d\n", s.Subject.Rating.Total)
+	text += s.ratingText()
 	text += ui.SecondaryText("\n收藏人数\n")
 	text += fmt.Sprintf("在看: %d\n", s.Subject.CollectionCount.Watching)
 	text += fmt.Sprintf("想看: %d\n", s.Subject.CollectionCount.Wish)
@@ -275,13 +474,31 @@ func (s *SubjectPage) createLeftText() string {
 	return text
 }
 
+// ratingText shows the vote distribution. The user's own rate is highlighted.
+func (s *SubjectPage) ratingText() string {
+	rating := s.Subject.Rating
+	if rating.Count.Total() == 0 {
+		return ""
+	}
+	var myRate uint32
+	if s.Collection != nil {
+		myRate = s.Collection.Rate
+	}
+	text := fmt.Sprintf("标准差: %.2f %s\n", rating.StdDev(), ui.TertiaryText(rating.Controversy()))
+	for i, line := range subject.RatingHistogram(rating, 20) {
+		if uint32(10-i) == myRate {
+			line = ui.SecondaryText(line)
+		}
+		text += line + "\n"
+	}
+	return text
+}
+
 func (s *SubjectPage) createRightText() string {
 	text := ""
 	text += fmt.Sprintf("%s\n", s.Subject.Summary)
 	text += "\n\n"
 	text += fmt.Sprintf("标签: %s\n", renderTags(s.Subject.Tags, s.Subject.WikiTags))
-	text += "\n\n"
-	text += fmt.Sprintf("正片:\n%s\n", renderEpisodes(s.Episodes))
 	return text
 }
 
@@ -302,42 +519,192 @@ func renderTags(tags []api.Tag, wikiTags []string) string {
 	return "| " + strings.Join(arr, " | ") + " |"
 }
 
-func renderEpisodes(episodes *api.Episodes) string {
-	if episodes == nil {
-		return "NULL"
+// episodeGridColumns is the number of episodes in a row of the grid
+const episodeGridColumns = 10
+
+// mainEpisodes returns main episodes. Specials, OPs and EDs are left out.
+func (s *SubjectPage) mainEpisodes() []api.Episode {
+	if s.Episodes == nil {
+		return nil
 	}
-	text := ""
+	var episodes []api.Episode
+	for _, ep := range s.Episodes.Data {
+		if ep.Type == 0 {
+			episodes = append(episodes, ep)
+		}
+	}
+	return episodes
+}
+
+// renderEpisodes fills the episode grid. Cells are colored by our status and air date.
+func (s *SubjectPage) renderEpisodes() {
+	row, column := s.episodeGrid.GetSelection()
+	s.episodeGrid.Clear()
+	episodes := s.mainEpisodes()
 	today := time.Now()
-	for _, ep := range episodes.Data {
-		if ep.Type != 0 {
-			continue // Skip non-episode types
+	for i, ep := range episodes {
+		s.episodeGrid.SetCell(i/episodeGridColumns, i%episodeGridColumns, episodeCell(ep, s.EpisodeStatus[ep.ID], today))
+	}
+	rows := (len(episodes) + episodeGridColumns - 1) / episodeGridColumns
+	s.right.ResizeItem(s.episodeGrid, min(rows, 8)+2, 0)
+	if len(episodes) == 0 {
+		s.episodeGrid.SetTitle("正片: 无")
+		return
+	}
+	// Keep the selection after toggling
+	if row*episodeGridColumns+column >= len(episodes) {
+		row, column = 0, 0
+	}
+	s.episodeGrid.Select(row, column)
+	s.episodeGrid.SetTitle(s.episodeTitle(row, column))
+}
+
+// episodeCell shows the episode number. Done is highlighted, wish is yellow,
+// dropped is red and struck through, and unaired episodes are grey.
+func episodeCell(ep api.Episode, status int, today time.Time) *tview.TableCell {
+	cell := tview.NewTableCell(fmt.Sprintf(" %3d ", ep.Sort)).SetReference(ep)
+	style := tcell.StyleDefault.Foreground(ui.Styles.PrimaryTextColor).Background(ui.Styles.PrimitiveBackgroundColor)
+	if airTime, err := ep.GetAirTime(); err != nil || dateCompare(airTime, today) > 0 {
+		style = style.Foreground(ui.GreyColor())
+	} else if dateCompare(airTime, today) == 0 {
+		style = style.Bold(true).Underline(true)
+	}
+	switch status {
+	case api.EpisodeCollectionType["done"]:
+		style = style.Foreground(ui.Styles.PrimaryTextColor).Background(ui.Styles.ContrastBackgroundColor)
+	case api.EpisodeCollectionType["wish"]:
+		style = style.Foreground(ui.Styles.SecondaryTextColor)
+	case api.EpisodeCollectionType["dropped"]:
+		style = style.Foreground(ui.Styles.GraphicsColor).StrikeThrough(true)
+	}
+	return cell.SetStyle(style)
+}
+
+var episodeStatusNames = map[int]string{
+	0: "未看",
+	1: "想看",
+	2: "看过",
+	3: "抛弃",
+}
+
+// episodeTitle describes the episode at a cell
+func (s *SubjectPage) episodeTitle(row, column int) string {
+	cell := s.episodeGrid.GetCell(row, column)
+	ep, ok := cell.GetReference().(api.Episode)
+	if !ok {
+		return "正片"
+	}
+	return fmt.Sprintf("%d. %s %s [%s]", ep.Sort, ep.GetName(), ep.Airdate, episodeStatusNames[s.EpisodeStatus[ep.ID]])
+}
+
+// handleEpisodeKeys marks the selected episode: toggle done, done, wish or dropped.
+func (s *SubjectPage) handleEpisodeKeys(event *tcell.EventKey) *tcell.EventKey {
+	action := s.app.keymap.Match(keymap.Episodes, event)
+	if action == "" {
+		return event
+	}
+	ep, ok := s.episodeGrid.GetCell(s.episodeGrid.GetSelection()).GetReference().(api.Episode)
+	if !ok {
+		return event
+	}
+	current := s.EpisodeStatus[ep.ID]
+	var status string
+	switch action {
+	case keymap.EpisodeToggle:
+		status = "done"
+		if current == api.EpisodeCollectionType["done"] {
+			status = "delete"
 		}
-		airTime, err := ep.GetAirTime()
+	case keymap.EpisodeDone:
+		status = "done"
+	case keymap.EpisodeWish:
+		status = "wish"
+	case keymap.EpisodeDropped:
+		status = "dropped"
+	}
+	s.setEpisodeStatus(ep, status)
+	return nil
+}
+
+// setEpisodeStatus updates the grid at once and rolls back if the API fails.
+func (s *SubjectPage) setEpisodeStatus(ep api.Episode, status string) {
+	if s.Collection == nil || s.Collection.Type == 0 {
+		s.app.NotifyWithStyle("Collect the subject before marking episodes", "warning")
+		return
+	}
+	original, hadStatus := s.EpisodeStatus[ep.ID]
+	s.EpisodeStatus[ep.ID] = api.EpisodeCollectionType[status]
+	s.updateEpStatus()
+
+	// Keyed by episode ID, so only a newer change of the same episode cancels this one
+	key := fmt.Sprintf("episode %d", ep.ID)
+	loader.Load(s.app.loader, key, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, subject.PutEpisode(s.app.User.Client.WithContext(ctx), ep.ID, status)
+	}, func(_ struct{}, err error) {
 		if err != nil {
-			slog.Error("Failed to get air time for episode", "Error", err, "Episode", ep.ID)
-			text += fmt.Sprintf("%d. %s %s\n", ep.Sort, ui.GraphicsColor(ep.GetName()), ui.Grey(ep.Airdate))
-			continue
+			if hadStatus {
+				s.EpisodeStatus[ep.ID] = original
+			} else {
+				delete(s.EpisodeStatus, ep.ID)
+			}
+			s.updateEpStatus()
 		}
-		diff := dateCompare(airTime, today)
-		if diff < 0 {
-			// On aired
-			text += fmt.Sprintf("%d. %s %s\n", ep.Sort, ep.GetName(), ui.Grey(ep.Airdate))
-		} else if diff == 0 {
-			// On airing today
-			text += fmt.Sprintf("%d. %s %s\n", ep.Sort, ui.SecondaryText(ep.GetName()), ui.Grey(ep.Airdate))
-		} else {
-			// Not yet on aired
-			text += fmt.Sprintf("%d. %s %s\n", ep.Sort, ui.GraphicsColor(ep.GetName()), ui.Grey(ep.Airdate))
+	})
+}
+
+// updateEpStatus counts watched episodes and redraws the page
+func (s *SubjectPage) updateEpStatus() {
+	done := 0
+	for _, ep := range s.mainEpisodes() {
+		if s.EpisodeStatus[ep.ID] == api.EpisodeCollectionType["done"] {
+			done++
 		}
 	}
-	return text
+	s.Collection.EpStatus = uint32(done)
+	s.leftContent.SetText(s.createLeftText())
+	s.renderEpisodes()
+}
+
+// cycleFocus moves focus from left to right content, then to the episode grid and related subjects
+func (s *SubjectPage) cycleFocus() {
+	switch {
+	case s.leftContent.HasFocus():
+		s.app.SetFocus(s.rightContent)
+	case s.rightContent.HasFocus():
+		s.app.SetFocus(s.episodeGrid)
+	case s.episodeGrid.HasFocus():
+		s.app.SetFocus(s.relatedList)
+	default:
+		s.app.SetFocus(s.leftContent)
+	}
 }
 
 // dateCompare returns the difference of a - b.
 // Not exact result, only for comparing dates.
+// Dates are compared in the timezone of a, e.g. JST for air dates.
 func dateCompare(a, b time.Time) int {
+	b = b.In(a.Location())
 	if a.Year() != b.Year() {
 		return a.Year() - b.Year()
 	}
 	return a.YearDay() - b.YearDay()
 }
+
+// selectedCollection returns the collection of the subject. nil before it is loaded.
+func (s *SubjectPage) selectedCollection() *api.UserSubjectCollection {
+	if s.Subject == nil {
+		return nil
+	}
+	return s.Collection
+}
+
+func (s *SubjectPage) paletteSubjects() []paletteSubject {
+	if s.Subject == nil {
+		return nil
+	}
+	subjects := []paletteSubject{{ID: int(s.Subject.ID), Name: s.Subject.Name, NameCn: s.Subject.NameCn}}
+	for _, r := range s.Related {
+		subjects = append(subjects, paletteSubject{ID: int(r.ID), Name: r.Name, NameCn: r.NameCn})
+	}
+	return subjects
+}
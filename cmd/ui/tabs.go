@@ -0,0 +1,77 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// TABS are the pages in the tab bar, in the order of their number keys
+var TABS = []string{"watching", "wish", "done", "stashed", "dropped", "calendar", "search", "stats", "season"}
+
+// TabBar shows the pages at the top with the current page highlighted.
+// Clicking a tab switches to its page.
+type TabBar struct {
+	*tview.TextView
+	app *App
+}
+
+func NewTabBar(a *App) *TabBar {
+	t := &TabBar{
+		TextView: tview.NewTextView().SetDynamicColors(true).SetRegions(true).SetWrap(false),
+		app:      a,
+	}
+	t.SetHighlightedFunc(func(added, removed, remaining []string) {
+		if len(added) == 0 || added[0] == a.currentPage {
+			return
+		}
+		a.Goto(added[0])
+		a.SetFocus(a.Pages)
+	})
+	// Keep the focus on the page when the bar is pressed
+	t.SetMouseCapture(func(action tview.MouseAction, event *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+		if action == tview.MouseLeftDown {
+			return tview.MouseConsumed, nil
+		}
+		return action, event
+	})
+	return t
+}
+
+// tabLabel is the title of a page with its count if known, e.g. "Watching (12)"
+func (t *TabBar) tabLabel(name string) string {
+	label := strings.ToUpper(name[:1]) + name[1:]
+	if page, ok := t.app.pages[name].(*CollectionPage); ok && len(page.Collections) > 0 {
+		label = fmt.Sprintf("%s (%d)", label, page.Total)
+	}
+	return label
+}
+
+// Update renders the tabs. Called when the page changes or counts are loaded.
+func (t *TabBar) Update() {
+	var b strings.Builder
+	for i, name := range TABS {
+		fmt.Fprintf(&b, `["%s"] %d %s [""] `, name, i+1, tview.Escape(t.tabLabel(name)))
+	}
+	// Pages without a tab are shown while open
+	switch t.app.currentPage {
+	case "subject":
+		if page, ok := t.app.pages["subject"].(*SubjectPage); ok && page.Subject != nil {
+			fmt.Fprintf(&b, `["subject"] %s [""]`, tview.Escape(page.Subject.GetName()))
+		} else {
+			b.WriteString(`["subject"] Subject [""]`)
+		}
+	case "user":
+		if page, ok := t.app.pages["user"].(*CollectionPage); ok {
+			fmt.Fprintf(&b, `["user"] %s %s [""]`, tview.Escape(page.Username), page.CollectionStatus)
+		}
+	case "backlog":
+		b.WriteString(`["backlog"] Backlog [""]`)
+	case "help":
+		b.WriteString(`["help"] Help [""]`)
+	}
+	t.SetText(b.String())
+	t.Highlight(t.app.currentPage)
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var theme string
+
 var uiCmd = &cobra.Command{
 	Use:   "ui",
 	Short: "Run terminal UI",
@@ -16,7 +18,10 @@ var uiCmd = &cobra.Command{
 		authClient := api.NewAuthClientWithConfig()
 		user := api.NewUser(authClient)
 		if user == nil {
-			auth.BrowserLogin(authClient)
+			if err := auth.BrowserLogin(authClient, auth.LoginOptions{}); err != nil {
+				fmt.Println(err)
+				return
+			}
 			// Try again after login
 			authClient = api.NewAuthClientWithConfig()
 			user = api.NewUser(authClient)
@@ -26,7 +31,7 @@ var uiCmd = &cobra.Command{
 			}
 		}
 
-		app := NewApp(user)
+		app := NewApp(user, theme)
 		err := app.Run()
 		if err != nil {
 			fmt.Println("Error running app:", err)
@@ -36,5 +41,6 @@ var uiCmd = &cobra.Command{
 }
 
 func init() {
+	uiCmd.Flags().StringVar(&theme, "theme", "", "color theme: dark, light, high-contrast, solarized, ansi, mono or a custom theme")
 	cmd.RootCmd.AddCommand(uiCmd)
 }
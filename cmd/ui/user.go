@@ -0,0 +1,61 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/iucario/bangumi-go/internal/ui"
+	"github.com/rivo/tview"
+)
+
+// UserModal asks for a username and opens the read-only collection page of the user.
+type UserModal struct {
+	*ui.Modal
+	app *App
+}
+
+func NewUserModal(a *App) *UserModal {
+	modal := &UserModal{
+		Modal: nil,
+		app:   a,
+	}
+	prevPage := a.currentPage
+	input := tview.NewInputField().SetLabel("Username").SetFieldWidth(30)
+	open := func() {
+		username := strings.TrimSpace(input.GetText())
+		modal.Close()
+		if username != "" {
+			a.OpenUserPage(username, prevPage)
+		}
+	}
+	input.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			open()
+		}
+	})
+
+	form := tview.NewForm()
+	form.SetBorder(true).SetTitle("Open user collection").SetTitleAlign(tview.AlignLeft)
+	form.AddFormItem(input)
+	form.AddButton("Open", open)
+	form.AddButton("Cancel", modal.Close)
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			modal.Close()
+			return nil
+		}
+		return event
+	})
+	modal.Modal = ui.NewModalForm("Open user collection", form)
+	modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+		if buttonIndex < 0 {
+			modal.Close()
+		}
+	})
+	return modal
+}
+
+func (m *UserModal) Close() {
+	m.app.Pages.RemovePage("username")
+	m.app.SetFocus(m.app.Pages)
+}
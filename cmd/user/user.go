@@ -0,0 +1,38 @@
+package user
+
+import (
+	"fmt"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd"
+	"github.com/spf13/cobra"
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user <username>",
+	Short: "Show public profile of a user",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		userInfo, err := api.GetUserInfoByName(api.NewHTTPClient(""), args[0])
+		api.AbortOnError(err)
+		printUserInfo(userInfo)
+	},
+}
+
+func printUserInfo(u *api.UserInfo) {
+	fmt.Printf("%s (%s)\n", u.Nickname, u.Username)
+	fmt.Printf("ID: %d\n", u.Id)
+	fmt.Printf("Group: %s\n", u.GroupName())
+	if u.Sign != "" {
+		fmt.Printf("Sign: %s\n", u.Sign)
+	}
+	fmt.Printf("Page: %s\n", u.URL())
+	fmt.Println("Avatar:")
+	fmt.Printf("  large:  %s\n", u.Avatar.Large)
+	fmt.Printf("  medium: %s\n", u.Avatar.Medium)
+	fmt.Printf("  small:  %s\n", u.Avatar.Small)
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(userCmd)
+}
@@ -0,0 +1,104 @@
+package watch
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd/calendar"
+	"github.com/iucario/bangumi-go/internal/notify"
+)
+
+// announceWindow is how long ago an episode of a newly watched subject may have aired to be announced.
+// Older episodes are recorded without a notification.
+const announceWindow = 24 * time.Hour
+
+// EpisodeNumber is the number of an episode in its season
+func EpisodeNumber(ep api.Episode) int {
+	if ep.Ep != 0 {
+		return ep.Ep
+	}
+	return ep.Sort
+}
+
+// Check updates the state with the main episodes aired by now and returns the notifications.
+// It announces each aired episode once and falling more than behind episodes behind,
+// until caught up. behind 0 disables the latter. Subjects no longer watched are dropped
+// from the state unless they are in keep, e.g. because their episodes failed to load.
+func Check(state *State, watched []calendar.Watched, keep []int, now time.Time, behind int) []notify.Notification {
+	var notifications []notify.Notification
+	current := make(map[int]bool, len(watched)+len(keep))
+	for _, id := range keep {
+		current[id] = true
+	}
+	for _, w := range watched {
+		id := int(w.Collection.SubjectID)
+		current[id] = true
+		s, known := state.Subjects[id]
+		if !known {
+			s = &SubjectState{}
+			state.Subjects[id] = s
+		}
+		name := w.Collection.Name()
+		latest, unwatched := 0, 0
+		for _, ep := range w.Episodes {
+			if ep.Type != 0 {
+				continue
+			}
+			aired, ok := w.AirTime(ep)
+			if !ok || aired.After(now) {
+				continue
+			}
+			number := EpisodeNumber(ep)
+			latest = max(latest, number)
+			if number > int(w.Collection.EpStatus) {
+				unwatched++
+			}
+			if s.announced(ep.ID) {
+				continue
+			}
+			s.Announced = append(s.Announced, ep.ID)
+			if !known && now.Sub(aired) > announceWindow {
+				continue
+			}
+			body := fmt.Sprintf("Episode %d aired", number)
+			if ep.Name != "" || ep.NameCn != "" {
+				body += ": " + ep.GetName()
+			}
+			notifications = append(notifications, notify.Notification{
+				Kind:      notify.NewEpisode,
+				Title:     name,
+				Body:      body,
+				URL:       fmt.Sprintf("https://bgm.tv/ep/%d", ep.ID),
+				SubjectID: id,
+				EpisodeID: ep.ID,
+				Episode:   number,
+				AiredAt:   aired,
+			})
+		}
+
+		if behind <= 0 || unwatched <= behind {
+			s.Behind = false
+			continue
+		}
+		if s.Behind {
+			continue
+		}
+		s.Behind = true
+		notifications = append(notifications, notify.Notification{
+			Kind:      notify.Behind,
+			Title:     name,
+			Body:      fmt.Sprintf("%d episodes behind, watched %d of %d", unwatched, w.Collection.EpStatus, latest),
+			URL:       fmt.Sprintf("https://bgm.tv/subject/%d", id),
+			SubjectID: id,
+			Episode:   latest,
+			Behind:    unwatched,
+		})
+	}
+	for id := range state.Subjects {
+		if !current[id] {
+			delete(state.Subjects, id)
+		}
+	}
+	return notifications
+}
@@ -0,0 +1,68 @@
+package watch
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/iucario/bangumi-go/util"
+)
+
+// State is what has been announced, kept in watch.json so that nothing is announced twice
+type State struct {
+	Subjects map[int]*SubjectState `json:"subjects"` // Subjects in the watching list by ID
+}
+
+// SubjectState is what has been announced about a subject
+type SubjectState struct {
+	Announced []int `json:"announced"` // IDs of aired episodes, announced or skipped
+	Behind    bool  `json:"behind"`    // Falling behind was announced and has not been caught up yet
+}
+
+// announced reports whether an episode has been announced
+func (s *SubjectState) announced(episodeID int) bool {
+	return slices.Contains(s.Announced, episodeID)
+}
+
+// StatePath returns the path of watch.json
+func StatePath() string {
+	return filepath.Join(util.ConfigDir(), "watch.json")
+}
+
+// LoadState reads watch.json. An empty state is returned if the file does not exist.
+func LoadState() (*State, error) {
+	state := &State{Subjects: make(map[int]*SubjectState)}
+	b, err := os.ReadFile(StatePath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(b, state); err != nil {
+		return state, err
+	}
+	if state.Subjects == nil {
+		state.Subjects = make(map[int]*SubjectState)
+	}
+	return state, nil
+}
+
+// Save writes watch.json. It replaces the file so a crash does not leave it half written.
+func (s *State) Save() error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(util.ConfigDir(), 0o755); err != nil {
+		return err
+	}
+	tmp := StatePath() + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, StatePath())
+}
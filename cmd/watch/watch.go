@@ -0,0 +1,127 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/iucario/bangumi-go/api"
+	"github.com/iucario/bangumi-go/cmd"
+	"github.com/iucario/bangumi-go/cmd/calendar"
+	"github.com/iucario/bangumi-go/internal/notify"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Notify about new episodes of your watching list",
+	Long: `Check the episodes of the anime in your watching list periodically and notify when
+an episode airs, or when more than --behind aired episodes are not watched.
+
+Notifications are printed to stdout and sent to the desktop, webhooks and commands
+given by flags. Webhooks receive a JSON POST. Commands run in a shell with the
+notification as JSON on stdin and in BGM_* environment variables.
+Announced episodes are saved in watch.json in the config directory.`,
+	Example: `bgm watch --desktop
+bgm watch --interval 1h --behind 5 --webhook https://example.com/hook
+bgm watch --once --quiet --exec 'jq -r .title >> ~/bgm.log'`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		interval, _ := cmd.Flags().GetDuration("interval")
+		behind, _ := cmd.Flags().GetInt("behind")
+		once, _ := cmd.Flags().GetBool("once")
+		if interval < time.Minute {
+			api.AbortOnError(fmt.Errorf("interval %s is shorter than a minute", interval))
+		}
+		sinks := sinksFromFlags(cmd)
+		if len(sinks) == 0 {
+			api.AbortOnError(errors.New("no sink, remove --quiet or add --desktop, --webhook or --exec"))
+		}
+		state, err := LoadState()
+		api.AbortOnError(err)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		for {
+			if err := run(ctx, state, sinks, behind); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "check failed: %v\n", err)
+				slog.Error("watch check failed", "error", err)
+			}
+			if once {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	},
+}
+
+func init() {
+	watchCmd.Flags().Duration("interval", 30*time.Minute, "Time between checks")
+	watchCmd.Flags().Int("behind", 3, "Notify when more aired episodes than this are not watched, 0 to disable")
+	watchCmd.Flags().Bool("once", false, "Check once and exit, e.g. from cron")
+	watchCmd.Flags().BoolP("quiet", "q", false, "Do not print notifications to stdout")
+	watchCmd.Flags().Bool("desktop", false, "Show desktop notifications with notify-send or osascript")
+	watchCmd.Flags().StringArray("webhook", nil, "POST notifications as JSON to the URL, can be repeated")
+	watchCmd.Flags().StringArray("exec", nil, "Run the shell command for each notification, can be repeated")
+	cmd.RootCmd.AddCommand(watchCmd)
+}
+
+func sinksFromFlags(cmd *cobra.Command) []notify.Sink {
+	var sinks []notify.Sink
+	if quiet, _ := cmd.Flags().GetBool("quiet"); !quiet {
+		sinks = append(sinks, notify.Writer{W: os.Stdout})
+	}
+	if desktop, _ := cmd.Flags().GetBool("desktop"); desktop {
+		sinks = append(sinks, notify.Desktop{})
+	}
+	webhooks, _ := cmd.Flags().GetStringArray("webhook")
+	for _, url := range webhooks {
+		sinks = append(sinks, notify.Webhook{URL: url, Client: &http.Client{Timeout: 30 * time.Second}})
+	}
+	commands, _ := cmd.Flags().GetStringArray("exec")
+	for _, command := range commands {
+		sinks = append(sinks, notify.Command{Command: command})
+	}
+	return sinks
+}
+
+// run checks once, sends the notifications and saves the state.
+// The credential is loaded each time so that a refreshed token is used.
+func run(ctx context.Context, state *State, sinks []notify.Sink, behind int) error {
+	client := api.NewAuthClientWithConfig().WithContext(ctx)
+	user := api.NewUser(client)
+	if user == nil {
+		return errors.New("failed to get user info, please login")
+	}
+	watched, failed, err := calendar.FetchWatched(client, user.Username)
+	if err != nil {
+		return err
+	}
+	for _, id := range failed {
+		slog.Warn("failed to get episodes", "subject", id)
+	}
+	notifications := Check(state, watched, failed, time.Now(), behind)
+	// Save first so a failing sink does not repeat notifications
+	if err := state.Save(); err != nil {
+		return err
+	}
+	for _, n := range notifications {
+		for _, sink := range sinks {
+			if err := sink.Send(ctx, n); err != nil {
+				fmt.Fprintf(os.Stderr, "notify: %v\n", err)
+				slog.Error("failed to send notification", "error", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/iucario/bangumi-go/internal/ui"
+	"github.com/iucario/bangumi-go/util"
+)
+
+// Config is the user configuration in config.json. Missing fields use defaults.
+type Config struct {
+	Keymap KeymapConfig        `json:"keymap"`
+	Theme  string              `json:"theme"`  // Name of a built-in or custom theme
+	Themes map[string]ui.Theme `json:"themes"` // Custom themes by name. Files in ThemesDir add more.
+	// Image protocol of covers: auto, kitty, iterm, sixel, halfblock or none
+	ImageProtocol string `json:"image_protocol"`
+	NoMouse       bool   `json:"no_mouse"` // Leave the mouse to the terminal, e.g. for selecting text
+	// IANA timezone of air times, e.g. "Europe/Berlin". Default is the local timezone.
+	Timezone string `json:"timezone"`
+	// Where the login is kept: auto, keyring, encrypted or file. BGM_CREDENTIAL_STORE overrides it.
+	CredentialStore string `json:"credential_store"`
+}
+
+// KeymapConfig selects a preset and overrides keys of some actions.
+type KeymapConfig struct {
+	Preset   string              `json:"preset"`   // vim or emacs. Default is vim.
+	Bindings map[string][]string `json:"bindings"` // Action name to keys. Replaces the keys of the preset.
+}
+
+// Path returns the path of config.json of the active profile.
+// A profile without one shares the config of the default profile.
+func Path() string {
+	path := filepath.Join(util.ConfigDir(), "config.json")
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return filepath.Join(util.BaseConfigDir(), "config.json")
+	}
+	return path
+}
+
+// Load reads config.json. The default config is returned if the file does not exist.
+func Load() (*Config, error) {
+	cfg := &Config{}
+	b, err := os.ReadFile(Path())
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return &Config{}, err
+	}
+	return cfg, nil
+}
+
+// ThemesDir is the directory of theme files next to config.json
+func ThemesDir() string {
+	return filepath.Join(filepath.Dir(Path()), "themes")
+}
+
+// LoadThemeFiles adds the themes in ThemesDir to Themes, e.g. themes/midnight.toml as "midnight".
+// Themes in config.json win over files of the same name. Invalid files are skipped and returned as an error.
+func (c *Config) LoadThemeFiles() error {
+	entries, err := os.ReadDir(ThemesDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || !slices.Contains(ui.ThemeExtensions, ext) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(ThemesDir(), entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		name, theme, err := ui.DecodeTheme(entry.Name(), data)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, ok := c.Themes[name]; ok {
+			continue
+		}
+		if c.Themes == nil {
+			c.Themes = make(map[string]ui.Theme)
+		}
+		c.Themes[name] = theme
+	}
+	return errors.Join(errs...)
+}
+
+// Location returns the timezone of air times
+func (c *Config) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.Local, fmt.Errorf("invalid timezone %q: %w", c.Timezone, err)
+	}
+	return loc, nil
+}
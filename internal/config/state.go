@@ -0,0 +1,75 @@
+// Package config stores settings and UI state in the config directory.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/iucario/bangumi-go/util"
+)
+
+// stateMu guards state.json. The TUI saves from several pages.
+var stateMu sync.Mutex
+
+// StatePath returns the path of state.json, UI state kept between runs.
+func StatePath() string {
+	return filepath.Join(util.ConfigDir(), "state.json")
+}
+
+// LoadState decodes the state saved under key into v.
+// v is left unchanged if nothing has been saved.
+func LoadState(key string, v any) error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	state, err := readState()
+	if err != nil {
+		return err
+	}
+	raw, ok := state[key]
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// SaveState saves v under key. State of other keys is kept.
+func SaveState(key string, v any) error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	state, err := readState()
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	state[key] = raw
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(util.ConfigDir(), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(StatePath(), b, 0o644)
+}
+
+func readState() (map[string]json.RawMessage, error) {
+	state := make(map[string]json.RawMessage)
+	b, err := os.ReadFile(StatePath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
@@ -0,0 +1,120 @@
+// Package cover downloads subject covers and renders them in terminals.
+package cover
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/iucario/bangumi-go/util"
+)
+
+// maxSize limits downloads. Covers are much smaller.
+const maxSize = 10 << 20
+
+var errTooLarge = fmt.Errorf("cover larger than %d MiB", maxSize>>20)
+
+// URL picks a cover of a reasonable size from the images of a subject
+func URL(images map[string]string) string {
+	for _, size := range []string{"common", "medium", "large", "small", "grid"} {
+		if url := images[size]; url != "" {
+			return url
+		}
+	}
+	return ""
+}
+
+// CacheDir returns the directory of downloaded covers
+func CacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(util.ConfigDir(), "cache", "covers")
+	}
+	return filepath.Join(dir, "bangumi-go", "covers")
+}
+
+// cachePath names the cached file by the hash of the URL, keeping the extension
+func cachePath(url string) string {
+	sum := sha1.Sum([]byte(url))
+	return filepath.Join(CacheDir(), hex.EncodeToString(sum[:])+path.Ext(url))
+}
+
+// Load returns the cover at url, downloading it on the first use.
+func Load(ctx context.Context, url string) (image.Image, error) {
+	if url == "" {
+		return nil, errors.New("no cover")
+	}
+	file := cachePath(url)
+	if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+		if err := download(ctx, url, file); err != nil {
+			return nil, err
+		}
+	}
+	return DecodeFile(file)
+}
+
+// DecodeFile decodes a JPEG, PNG or GIF file
+func DecodeFile(file string) (image.Image, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", file, err)
+	}
+	return img, nil
+}
+
+// download writes to a temporary file first so a failed or too large download is not cached
+func download(ctx context.Context, url, file string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download %s: %s", url, resp.Status)
+	}
+	if resp.ContentLength > maxSize {
+		return fmt.Errorf("download %s: %w", url, errTooLarge)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), "download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	// One byte more than the limit tells a large body from one of exactly maxSize
+	n, err := io.Copy(tmp, io.LimitReader(resp.Body, maxSize+1))
+	if err == nil && n > maxSize {
+		err = fmt.Errorf("download %s: %w", url, errTooLarge)
+	}
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,79 @@
+package cover
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownload(t *testing.T) {
+	cover, err := os.ReadFile(filepath.Join("testdata", "cover.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	large := bytes.Repeat([]byte{0}, maxSize+1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cover.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(cover)
+	})
+	mux.HandleFunc("/large.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(large)
+	})
+	mux.HandleFunc("/chunked.png", func(w http.ResponseWriter, r *http.Request) {
+		// Flushing before the end sends no Content-Length, so only reading finds the size
+		w.Write(large[:1024])
+		w.(http.Flusher).Flush()
+		w.Write(large[1024:])
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		path    string
+		wantErr error
+	}{
+		{"/cover.png", nil},
+		{"/large.png", errTooLarge},
+		{"/chunked.png", errTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "cover.png")
+			err := download(context.Background(), srv.URL+tt.path, file)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("download = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if _, err := os.Stat(file); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("rejected download is cached: %v", err)
+				}
+				entries, _ := os.ReadDir(filepath.Dir(file))
+				if len(entries) != 0 {
+					t.Errorf("temporary files left: %v", entries)
+				}
+				return
+			}
+			if _, err := DecodeFile(file); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDownloadStatus(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	file := filepath.Join(t.TempDir(), "cover.png")
+	if err := download(context.Background(), srv.URL+"/cover.png", file); err == nil {
+		t.Fatal("404 downloaded")
+	}
+	if _, err := os.Stat(file); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("failed download is cached: %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+package cover
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// Cells are assumed to be twice as tall as wide and this many pixels in size.
+// Sixel images are sized in pixels and use it.
+const (
+	cellWidth  = 10
+	cellHeight = 20
+)
+
+// Fit returns the size in cells of an image fitting in cols x rows, keeping the aspect ratio.
+func Fit(img image.Image, cols, rows int) (int, int) {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 || cols <= 0 || rows <= 0 {
+		return 0, 0
+	}
+	// Height in cells if the image is cols wide
+	h := cols * b.Dy() * cellWidth / (b.Dx() * cellHeight)
+	if h <= rows {
+		return cols, max(1, h)
+	}
+	w := rows * b.Dx() * cellHeight / (b.Dy() * cellWidth)
+	return max(1, w), rows
+}
+
+// Resize scales an image to w x h pixels by averaging the source pixels of each target pixel.
+func Resize(img image.Image, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	b := img.Bounds()
+	if b.Empty() {
+		return dst
+	}
+	for y := range h {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := range w {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/w)
+			var r, g, bl, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, _ := img.At(sx, sy).RGBA()
+					r, g, bl, n = r+cr, g+cg, bl+cb, n+1
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(bl / n >> 8), 0xff})
+		}
+	}
+	return dst
+}
+
+// HalfBlocks scales an image to cols x rows cells. Each cell is the upper half block
+// with the top pixel as foreground and the bottom pixel as background.
+// The returned pairs are indexed by row then column.
+func HalfBlocks(img image.Image, cols, rows int) [][][2]color.RGBA {
+	small := Resize(img, cols, rows*2)
+	cells := make([][][2]color.RGBA, rows)
+	for y := range rows {
+		cells[y] = make([][2]color.RGBA, cols)
+		for x := range cols {
+			cells[y][x] = [2]color.RGBA{small.RGBAAt(x, 2*y), small.RGBAAt(x, 2*y+1)}
+		}
+	}
+	return cells
+}
+
+// Encode writes the image in cols x rows cells at the cursor using the protocol.
+func Encode(w io.Writer, img image.Image, cols, rows int, p Protocol) error {
+	bw := bufio.NewWriter(w)
+	var err error
+	switch p {
+	case Kitty:
+		err = encodeKitty(bw, img, cols, rows)
+	case ITerm:
+		err = encodeITerm(bw, img, cols, rows)
+	case Sixel:
+		err = encodeSixel(bw, img, cols, rows)
+	default:
+		encodeHalfBlocks(bw, img, cols, rows)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func encodeHalfBlocks(w io.Writer, img image.Image, cols, rows int) {
+	for i, row := range HalfBlocks(img, cols, rows) {
+		if i > 0 {
+			fmt.Fprint(w, "\n")
+		}
+		for _, cell := range row {
+			top, bottom := cell[0], cell[1]
+			fmt.Fprintf(w, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", top.R, top.G, top.B, bottom.R, bottom.G, bottom.B)
+		}
+		fmt.Fprint(w, "\x1b[0m")
+	}
+}
+
+func pngBase64(img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// encodeKitty sends a PNG in chunks of 4096 bytes. The cursor is not moved.
+func encodeKitty(w io.Writer, img image.Image, cols, rows int) error {
+	data, err := pngBase64(img)
+	if err != nil {
+		return err
+	}
+	const chunk = 4096
+	for i := 0; i < len(data); i += chunk {
+		end := min(i+chunk, len(data))
+		more := 0
+		if end < len(data) {
+			more = 1
+		}
+		if i == 0 {
+			fmt.Fprintf(w, "\x1b_Ga=T,f=100,q=2,C=1,c=%d,r=%d,m=%d;%s\x1b\\", cols, rows, more, data[i:end])
+		} else {
+			fmt.Fprintf(w, "\x1b_Gm=%d;%s\x1b\\", more, data[i:end])
+		}
+	}
+	return nil
+}
+
+// ClearKitty deletes all images placed by Kitty
+func ClearKitty(w io.Writer) {
+	fmt.Fprint(w, "\x1b_Ga=d,q=2\x1b\\")
+}
+
+func encodeITerm(w io.Writer, img image.Image, cols, rows int) error {
+	data, err := pngBase64(img)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\x1b]1337;File=inline=1;width=%d;height=%d;preserveAspectRatio=1:%s\a", cols, rows, data)
+	return nil
+}
+
+// encodeSixel dithers the image to the web-safe palette and writes it in bands of six pixel rows.
+func encodeSixel(w io.Writer, img image.Image, cols, rows int) error {
+	width, height := cols*cellWidth, rows*cellHeight
+	small := Resize(img, width, height)
+	paletted := image.NewPaletted(small.Bounds(), palette.WebSafe)
+	draw.FloydSteinberg.Draw(paletted, paletted.Bounds(), small, image.Point{})
+
+	fmt.Fprintf(w, "\x1bPq\"1;1;%d;%d", width, height)
+	for i, c := range palette.WebSafe {
+		r, g, b, _ := c.RGBA()
+		fmt.Fprintf(w, "#%d;2;%d;%d;%d", i, r*100/0xffff, g*100/0xffff, b*100/0xffff)
+	}
+	var line strings.Builder
+	for top := 0; top < height; top += 6 {
+		// Colors in the band, in order of first use
+		var used []uint8
+		seen := make(map[uint8]bool)
+		for y := top; y < min(top+6, height); y++ {
+			for x := range width {
+				index := paletted.ColorIndexAt(x, y)
+				if !seen[index] {
+					seen[index] = true
+					used = append(used, index)
+				}
+			}
+		}
+		for i, index := range used {
+			if i > 0 {
+				line.WriteByte('$')
+			}
+			fmt.Fprintf(&line, "#%d", index)
+			writeSixelRun(&line, paletted, index, top, width, height)
+		}
+		line.WriteByte('-')
+		if _, err := io.WriteString(w, line.String()); err != nil {
+			return err
+		}
+		line.Reset()
+	}
+	fmt.Fprint(w, "\x1b\\")
+	return nil
+}
+
+// writeSixelRun writes the pixels of one color in a band, compressing repeats
+func writeSixelRun(b *strings.Builder, img *image.Paletted, index uint8, top, width, height int) {
+	var last byte
+	count := 0
+	flush := func() {
+		switch {
+		case count > 3:
+			fmt.Fprintf(b, "!%d%c", count, last)
+		default:
+			for range count {
+				b.WriteByte(last)
+			}
+		}
+	}
+	for x := range width {
+		var bits byte
+		for dy := 0; dy < 6 && top+dy < height; dy++ {
+			if img.ColorIndexAt(x, top+dy) == index {
+				bits |= 1 << dy
+			}
+		}
+		char := 63 + bits
+		if char == last {
+			count++
+			continue
+		}
+		flush()
+		last, count = char, 1
+	}
+	flush()
+}
@@ -0,0 +1,189 @@
+package cover
+
+import (
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// testCover is testdata/cover.png, an 8x12 gradient
+func testCover(t *testing.T) image.Image {
+	t.Helper()
+	img, err := DecodeFile(filepath.Join("testdata", "cover.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	file := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(file, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s, run go test -update if the change is intended", file)
+	}
+}
+
+func TestDecodeFile(t *testing.T) {
+	img := testCover(t)
+	if got := img.Bounds(); got != image.Rect(0, 0, 8, 12) {
+		t.Errorf("bounds = %v, want 8x12", got)
+	}
+	if _, err := DecodeFile(filepath.Join("testdata", "missing.png")); err == nil {
+		t.Error("missing file decoded")
+	}
+}
+
+func TestFit(t *testing.T) {
+	img := testCover(t)
+	tests := []struct {
+		cols, rows         int
+		wantCols, wantRows int
+	}{
+		{4, 10, 4, 3}, // limited by width
+		{40, 3, 4, 3}, // limited by height
+		{20, 100, 20, 15},
+		{0, 10, 0, 0},
+	}
+	for _, tt := range tests {
+		cols, rows := Fit(img, tt.cols, tt.rows)
+		if cols != tt.wantCols || rows != tt.wantRows {
+			t.Errorf("Fit(%d, %d) = %d, %d, want %d, %d", tt.cols, tt.rows, cols, rows, tt.wantCols, tt.wantRows)
+		}
+	}
+}
+
+func TestEncodeHalfBlocks(t *testing.T) {
+	var b bytes.Buffer
+	if err := Encode(&b, testCover(t), 4, 3, HalfBlock); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(b.String(), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("%d rows, want 3", len(lines))
+	}
+	for i, line := range lines {
+		if n := strings.Count(line, "▀"); n != 4 {
+			t.Errorf("row %d has %d cells, want 4", i, n)
+		}
+	}
+	golden(t, "cover.halfblock", b.Bytes())
+}
+
+func TestEncodeSixel(t *testing.T) {
+	var b bytes.Buffer
+	if err := Encode(&b, testCover(t), 4, 3, Sixel); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if header := fmt.Sprintf("\x1bPq\"1;1;%d;%d", 4*cellWidth, 3*cellHeight); !strings.HasPrefix(out, header) {
+		t.Errorf("output starts with %q, want %q", out[:min(len(out), 20)], header)
+	}
+	if !strings.HasSuffix(out, "\x1b\\") {
+		t.Error("sixel is not terminated")
+	}
+	// One band of six pixel rows ends with -
+	if bands := strings.Count(out, "-"); bands != 3*cellHeight/6 {
+		t.Errorf("%d bands, want %d", bands, 3*cellHeight/6)
+	}
+	golden(t, "cover.sixel", b.Bytes())
+}
+
+var kittyChunk = regexp.MustCompile(`\x1b_G([^;]*);([^\x1b]*)\x1b\\`)
+
+// kittyPayload returns the control data of each chunk and the joined base64 payload
+func kittyPayload(t *testing.T, out string) ([]string, string) {
+	t.Helper()
+	var controls []string
+	var data strings.Builder
+	for _, m := range kittyChunk.FindAllStringSubmatch(out, -1) {
+		controls = append(controls, m[1])
+		if len(m[2]) > 4096 {
+			t.Errorf("chunk of %d bytes, want at most 4096", len(m[2]))
+		}
+		data.WriteString(m[2])
+	}
+	if len(controls) == 0 {
+		t.Fatalf("no kitty chunks in %q", out)
+	}
+	return controls, data.String()
+}
+
+func TestEncodeKitty(t *testing.T) {
+	img := testCover(t)
+	var b bytes.Buffer
+	if err := Encode(&b, img, 4, 3, Kitty); err != nil {
+		t.Fatal(err)
+	}
+	controls, data := kittyPayload(t, b.String())
+	if want := "a=T,f=100,q=2,C=1,c=4,r=3,m=0"; len(controls) != 1 || controls[0] != want {
+		t.Errorf("controls = %q, want [%q]", controls, want)
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The terminal scales the image, so the pixels are sent as they are
+	bounds := img.Bounds()
+	if decoded.Bounds() != bounds {
+		t.Fatalf("sent %v, want %v", decoded.Bounds(), bounds)
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if color.RGBAModel.Convert(decoded.At(x, y)) != color.RGBAModel.Convert(img.At(x, y)) {
+				t.Fatalf("pixel %d,%d differs", x, y)
+			}
+		}
+	}
+}
+
+func TestEncodeKittyChunks(t *testing.T) {
+	// Noise does not compress, so the PNG needs several chunks
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	seed := uint32(1)
+	for i := range img.Pix {
+		seed = seed*1664525 + 1013904223
+		img.Pix[i] = uint8(seed >> 24)
+	}
+	var b bytes.Buffer
+	if err := Encode(&b, img, 10, 5, Kitty); err != nil {
+		t.Fatal(err)
+	}
+	controls, _ := kittyPayload(t, b.String())
+	if len(controls) < 2 {
+		t.Fatalf("%d chunks, want several", len(controls))
+	}
+	for i, control := range controls {
+		more := "m=1"
+		if i == len(controls)-1 {
+			more = "m=0"
+		}
+		if !strings.HasSuffix(control, more) {
+			t.Errorf("chunk %d has %q, want %s", i, control, more)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package cover
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Protocol is how images are drawn in the terminal
+type Protocol string
+
+const (
+	HalfBlock Protocol = "halfblock" // Unicode half blocks with true colors, works everywhere
+	Kitty     Protocol = "kitty"
+	ITerm     Protocol = "iterm"
+	Sixel     Protocol = "sixel"
+	None      Protocol = "none"
+)
+
+var PROTOCOLS = []Protocol{HalfBlock, Kitty, ITerm, Sixel, None}
+
+// ParseProtocol parses a protocol name. "auto" and empty detect the protocol.
+func ParseProtocol(s string) (Protocol, error) {
+	if s == "" || s == "auto" {
+		return Detect(), nil
+	}
+	for _, p := range PROTOCOLS {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown image protocol %q", s)
+}
+
+// Detect guesses the best protocol from the environment.
+// BGM_IMAGE_PROTOCOL overrides the guess.
+func Detect() Protocol {
+	if p := Protocol(os.Getenv("BGM_IMAGE_PROTOCOL")); p != "" {
+		for _, known := range PROTOCOLS {
+			if p == known {
+				return p
+			}
+		}
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return None
+	}
+	// Multiplexers do not pass graphics through
+	if os.Getenv("TMUX") != "" || strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		return HalfBlock
+	}
+	term := os.Getenv("TERM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || term == "xterm-ghostty":
+		return Kitty
+	case os.Getenv("TERM_PROGRAM") == "iTerm.app" || os.Getenv("TERM_PROGRAM") == "WezTerm":
+		return ITerm
+	case term == "foot" || term == "mlterm" || strings.Contains(term, "sixel"):
+		return Sixel
+	}
+	return HalfBlock
+}
@@ -0,0 +1,284 @@
+// Package credstore keeps the credential of a profile in the OS keyring, an encrypted file or a plain file.
+package credstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// ErrNotFound is returned by Load when no credential is stored
+var ErrNotFound = errors.New("credential not found")
+
+// Store keeps the credential of one profile as opaque bytes
+type Store interface {
+	Name() string
+	Load() ([]byte, error) // ErrNotFound if nothing is stored
+	Save(data []byte) error
+	Delete() error // Deleting a missing credential is not an error
+}
+
+// Kinds of stores selected by credential_store in the config or BGM_CREDENTIAL_STORE
+const (
+	Auto      = "auto"      // Keyring if available, else File
+	Keyring   = "keyring"   // Secret Service through secret-tool
+	Encrypted = "encrypted" // Password-encrypted file
+	File      = "file"      // Plain JSON file readable only by the user
+	InMemory  = "memory"    // Nothing is persisted, for tests
+)
+
+// Options locate the credential of a profile
+type Options struct {
+	Dir     string // Profile directory with the credential files
+	Profile string // Profile name, the key in the keyring
+	// Password returns the password of the encrypted file. confirm is true when the file is created.
+	Password func(confirm bool) ([]byte, error)
+	// ForgetPassword is called when the password is wrong, to clear a cached one. Optional.
+	ForgetPassword func()
+	Warn           io.Writer // Warnings about plain text credentials, nil to discard
+}
+
+// Open returns the store of a kind. Empty is Auto.
+func Open(kind string, opts Options) (Store, error) {
+	switch kind {
+	case "", Auto:
+		if KeyringAvailable() {
+			return &SecretService{Profile: opts.Profile}, nil
+		}
+		return &PlainFile{Path: PlainPath(opts.Dir), Warn: opts.Warn}, nil
+	case Keyring:
+		return &SecretService{Profile: opts.Profile}, nil
+	case Encrypted:
+		if opts.Password == nil {
+			return nil, errors.New("encrypted credential store needs a password")
+		}
+		return &EncryptedFile{
+			Path:           filepath.Join(opts.Dir, "credential.enc"),
+			Password:       opts.Password,
+			ForgetPassword: opts.ForgetPassword,
+		}, nil
+	case File:
+		return &PlainFile{Path: PlainPath(opts.Dir), Warn: opts.Warn}, nil
+	case InMemory:
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q, must be auto, keyring, encrypted or file", kind)
+	}
+}
+
+// PlainPath is credential.json in a profile directory, where credentials were kept before stores
+func PlainPath(dir string) string {
+	return filepath.Join(dir, "credential.json")
+}
+
+// Load reads the credential from store. A credential found only in old is moved to
+// store first, so plain text credentials of earlier versions migrate on first use.
+func Load(store, old Store) ([]byte, error) {
+	data, err := store.Load()
+	if !errors.Is(err, ErrNotFound) || old == nil || old.Name() == store.Name() {
+		return data, err
+	}
+	data, err = old.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Save(data); err != nil {
+		return nil, fmt.Errorf("migrate credential to %s: %w", store.Name(), err)
+	}
+	if err := old.Delete(); err != nil {
+		return data, fmt.Errorf("delete migrated credential: %w", err)
+	}
+	return data, nil
+}
+
+// Memory keeps the credential in memory
+type Memory struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Name() string {
+	return InMemory
+}
+
+func (m *Memory) Load() ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), m.data...), nil
+}
+
+func (m *Memory) Save(data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data = append([]byte{}, data...)
+	return nil
+}
+
+func (m *Memory) Delete() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data = nil
+	return nil
+}
+
+// PlainFile is a JSON file with mode 0600. Files readable by others are fixed on load.
+type PlainFile struct {
+	Path string
+	Warn io.Writer
+	once sync.Once
+}
+
+func (f *PlainFile) Name() string {
+	return File
+}
+
+func (f *PlainFile) Load() ([]byte, error) {
+	info, err := os.Stat(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		if err := os.Chmod(f.Path, 0o600); err != nil {
+			return nil, err
+		}
+		f.warn(fmt.Sprintf("%s was readable by other users and is now mode 0600", f.Path))
+	}
+	return os.ReadFile(f.Path)
+}
+
+func (f *PlainFile) Save(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+	if err := writeFile(f.Path, data); err != nil {
+		return err
+	}
+	f.warn(fmt.Sprintf("credential saved in plain text at %s, set credential_store to keyring or encrypted to protect it", f.Path))
+	return nil
+}
+
+func (f *PlainFile) Delete() error {
+	err := os.Remove(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// warn writes a warning once per store
+func (f *PlainFile) warn(message string) {
+	if f.Warn == nil {
+		return
+	}
+	f.once.Do(func() {
+		fmt.Fprintf(f.Warn, "warning: %s\n", message)
+	})
+}
+
+// writeFile replaces a file with mode 0600 so the credential is never readable by others
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// secretService is the attribute that marks credentials of this app in the keyring
+const secretService = "bangumi-go"
+
+// SecretService stores credentials in the freedesktop Secret Service, e.g. GNOME Keyring
+// or KWallet, with secret-tool from libsecret
+type SecretService struct {
+	Profile string
+}
+
+// KeyringAvailable reports whether secret-tool and a D-Bus session are available
+func KeyringAvailable() bool {
+	if runtime.GOOS != "linux" && runtime.GOOS != "freebsd" && runtime.GOOS != "openbsd" {
+		return false
+	}
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return false
+	}
+	_, err := exec.LookPath("secret-tool")
+	return err == nil
+}
+
+func (s *SecretService) Name() string {
+	return Keyring
+}
+
+func (s *SecretService) attributes() []string {
+	return []string{"service", secretService, "profile", s.Profile}
+}
+
+func (s *SecretService) Load() ([]byte, error) {
+	cmd := exec.Command("secret-tool", append([]string{"lookup"}, s.attributes()...)...)
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	// lookup exits with 1 and prints nothing if the secret is missing
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(out) == 0 && len(exitErr.Stderr) == 0 {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, secretToolError(err)
+	}
+	if len(out) == 0 {
+		return nil, ErrNotFound
+	}
+	return out, nil
+}
+
+func (s *SecretService) Save(data []byte) error {
+	label := fmt.Sprintf("--label=bangumi-go credential (%s)", s.Profile)
+	cmd := exec.Command("secret-tool", append([]string{"store", label}, s.attributes()...)...)
+	cmd.Stdin = bytes.NewReader(data)
+	if _, err := cmd.Output(); err != nil {
+		return secretToolError(err)
+	}
+	return nil
+}
+
+func (s *SecretService) Delete() error {
+	cmd := exec.Command("secret-tool", append([]string{"clear"}, s.attributes()...)...)
+	if _, err := cmd.Output(); err != nil {
+		var exitErr *exec.ExitError
+		// clear exits with 1 if nothing matched
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) == 0 {
+			return nil
+		}
+		return secretToolError(err)
+	}
+	return nil
+}
+
+func secretToolError(err error) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return errors.New("secret-tool not found, install libsecret-tools or set credential_store to encrypted")
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("secret-tool: %s", exitErr.Stderr)
+	}
+	return fmt.Errorf("secret-tool: %w", err)
+}
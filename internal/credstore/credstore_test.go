@@ -0,0 +1,184 @@
+package credstore
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const credential = `{"access_token":"token","user_id":1}`
+
+func TestLoadMigratesPlainFile(t *testing.T) {
+	dir := t.TempDir()
+	plain := &PlainFile{Path: PlainPath(dir)}
+	if err := os.WriteFile(plain.Path, []byte(credential), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemory()
+
+	data, err := Load(store, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != credential {
+		t.Errorf("Load = %s, want %s", data, credential)
+	}
+	if _, err := os.Stat(plain.Path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("plain credential is kept after migration: %v", err)
+	}
+	if stored, err := store.Load(); err != nil || string(stored) != credential {
+		t.Errorf("store has %s, %v, want %s", stored, err, credential)
+	}
+	// The migrated credential is read from the store from now on
+	if data, err := Load(store, plain); err != nil || string(data) != credential {
+		t.Errorf("second Load = %s, %v", data, err)
+	}
+}
+
+func TestLoadNotFound(t *testing.T) {
+	plain := &PlainFile{Path: PlainPath(t.TempDir())}
+	if _, err := Load(NewMemory(), plain); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPlainFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on Windows")
+	}
+	dir := t.TempDir()
+	var warn bytes.Buffer
+	f := &PlainFile{Path: filepath.Join(dir, "profile", "credential.json"), Warn: &warn}
+	if err := f.Save([]byte(credential)); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("mode = %o, want 600", mode)
+	}
+	if !strings.Contains(warn.String(), "plain text") {
+		t.Errorf("no plain text warning, got %q", warn.String())
+	}
+
+	// A file readable by others is fixed on load
+	if err := os.Chmod(f.Path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f = &PlainFile{Path: f.Path}
+	if _, err := f.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(f.Path); info.Mode().Perm() != 0o600 {
+		t.Errorf("mode after load = %o, want 600", info.Mode().Perm())
+	}
+}
+
+// password returns the password function of a store and records the confirm arguments
+func password(p string, confirms *[]bool) func(confirm bool) ([]byte, error) {
+	return func(confirm bool) ([]byte, error) {
+		*confirms = append(*confirms, confirm)
+		return []byte(p), nil
+	}
+}
+
+func TestEncryptedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credential.enc")
+	var confirms []bool
+	f := &EncryptedFile{Path: path, Password: password("secret", &confirms)}
+	if _, err := f.Load(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Load of missing file = %v, want ErrNotFound", err)
+	}
+	if err := f.Save([]byte(credential)); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("token")) {
+		t.Error("credential is stored in plain text")
+	}
+	data, err := f.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != credential {
+		t.Errorf("Load = %s, want %s", data, credential)
+	}
+	// Only a new file asks to confirm the password
+	if err := f.Save([]byte(credential)); err != nil {
+		t.Fatal(err)
+	}
+	if want := []bool{true, false, false}; !slices.Equal(confirms, want) {
+		t.Errorf("confirm = %v, want %v", confirms, want)
+	}
+}
+
+func TestEncryptedFileWrongPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credential.enc")
+	var confirms []bool
+	if err := (&EncryptedFile{Path: path, Password: password("secret", &confirms)}).Save([]byte(credential)); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrong := &EncryptedFile{Path: path, Password: password("typo", &confirms)}
+	if _, err := wrong.Load(); err == nil {
+		t.Error("Load with a wrong password succeeded")
+	}
+	if err := wrong.Save([]byte(`{"access_token":"other"}`)); err == nil {
+		t.Error("Save with a wrong password replaced the credential")
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("credential file changed after a failed save")
+	}
+}
+
+func TestPasswordCacheForgetsWrongPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credential.enc")
+	var confirms []bool
+	if err := (&EncryptedFile{Path: path, Password: password("secret", &confirms)}).Save([]byte(credential)); err != nil {
+		t.Fatal(err)
+	}
+
+	answers := []string{"typo", "secret"}
+	cache := &PasswordCache{Prompt: func(confirm bool) ([]byte, error) {
+		p := answers[0]
+		answers = answers[1:]
+		return []byte(p), nil
+	}}
+	f := &EncryptedFile{Path: path, Password: cache.Password, ForgetPassword: cache.Forget}
+	if _, err := f.Load(); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("Load with a wrong password = %v, want ErrWrongPassword", err)
+	}
+	// The wrong password is not reused, the next load asks again
+	data, err := f.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != credential {
+		t.Errorf("Load = %s, want %s", data, credential)
+	}
+	// The right password is cached
+	if _, err := f.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(answers) != 0 {
+		t.Errorf("asked %d times, want 2", 2-len(answers))
+	}
+}
@@ -0,0 +1,222 @@
+package credstore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// Parameters of new encrypted files. Files keep their own iterations so they can be raised later.
+const (
+	kdfIterations = 600_000
+	saltSize      = 16
+	keySize       = 32 // AES-256
+)
+
+// additionalData binds the ciphertext to its purpose
+var additionalData = []byte("bangumi-go credential v1")
+
+// ErrWrongPassword is returned when the password does not open the encrypted file
+var ErrWrongPassword = errors.New("wrong credential password or corrupted credential file")
+
+// encryptedFile is the JSON format of credential.enc
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// EncryptedFile encrypts the credential with AES-256-GCM and a key derived from a password with PBKDF2-SHA256
+type EncryptedFile struct {
+	Path     string
+	Password func(confirm bool) ([]byte, error)
+	// ForgetPassword is called when the password does not open the file, so a cached one is asked again. Optional.
+	ForgetPassword func()
+}
+
+func (f *EncryptedFile) Name() string {
+	return Encrypted
+}
+
+func (f *EncryptedFile) Load() ([]byte, error) {
+	b, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	password, err := f.Password(false)
+	if err != nil {
+		return nil, err
+	}
+	return f.open(b, password)
+}
+
+// open decrypts b and forgets a password that does not open it
+func (f *EncryptedFile) open(b, password []byte) ([]byte, error) {
+	data, err := f.decrypt(b, password)
+	if errors.Is(err, ErrWrongPassword) && f.ForgetPassword != nil {
+		f.ForgetPassword()
+	}
+	return data, err
+}
+
+// decrypt opens the content of an encrypted file with password
+func (f *EncryptedFile) decrypt(b, password []byte) ([]byte, error) {
+	var file encryptedFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", f.Path, err)
+	}
+	if file.Version != 1 || file.KDF != "pbkdf2-sha256" {
+		return nil, fmt.Errorf("unsupported %s version %d", f.Path, file.Version)
+	}
+	gcm, err := newGCM(password, file.Salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	data, err := gcm.Open(nil, file.Nonce, file.Ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+	return data, nil
+}
+
+// Save encrypts data. A new file asks for the password twice. An existing file must
+// open with the password, so a typo cannot lock the credential with an unknown one.
+func (f *EncryptedFile) Save(data []byte) error {
+	old, err := os.ReadFile(f.Path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	password, err := f.Password(!exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if _, err := f.open(old, password); err != nil {
+			return err
+		}
+	}
+	file := encryptedFile{Version: 1, KDF: "pbkdf2-sha256", Iterations: kdfIterations, Salt: make([]byte, saltSize)}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+	gcm, err := newGCM(password, file.Salt, file.Iterations)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Ciphertext = gcm.Seal(nil, file.Nonce, data, additionalData)
+	b, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+	return writeFile(f.Path, b)
+}
+
+func (f *EncryptedFile) Delete() error {
+	err := os.Remove(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func newGCM(password, salt []byte, iterations int) (cipher.AEAD, error) {
+	if len(password) == 0 {
+		return nil, errors.New("empty credential password")
+	}
+	key, err := pbkdf2.Key(sha256.New, string(password), salt, iterations, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// PasswordCache asks with Prompt once per process and reuses the answer,
+// so refreshing a token does not ask again
+type PasswordCache struct {
+	Prompt func(confirm bool) ([]byte, error)
+
+	mu       sync.Mutex
+	password []byte
+}
+
+// Password returns the cached password, or asks for it
+func (c *PasswordCache) Password(confirm bool) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.password != nil {
+		return c.password, nil
+	}
+	p, err := c.Prompt(confirm)
+	if err != nil {
+		return nil, err
+	}
+	c.password = p
+	return c.password, nil
+}
+
+// Forget drops the cached password, e.g. after it did not open the file
+func (c *PasswordCache) Forget() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.password = nil
+}
+
+// PromptPassword reads the password from BGM_CREDENTIAL_PASSWORD, or asks on the terminal.
+// A new password is asked twice.
+func PromptPassword(confirm bool) ([]byte, error) {
+	if p := os.Getenv("BGM_CREDENTIAL_PASSWORD"); p != "" {
+		return []byte(p), nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("credential password needed, set BGM_CREDENTIAL_PASSWORD")
+	}
+	password, err := readPassword(fd, "Credential password: ")
+	if err != nil || !confirm {
+		return password, err
+	}
+	again, err := readPassword(fd, "Repeat credential password: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(password, again) {
+		return nil, errors.New("passwords do not match")
+	}
+	return password, nil
+}
+
+func readPassword(fd int, prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return password, err
+}
@@ -0,0 +1,392 @@
+// Package keymap maps keys to named actions of the TUI.
+package keymap
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Action is a named command that keys can be bound to
+type Action string
+
+const (
+	GotoWatching Action = "goto.watching"
+	GotoWish     Action = "goto.wish"
+	GotoDone     Action = "goto.done"
+	GotoStashed  Action = "goto.stashed"
+	GotoDropped  Action = "goto.dropped"
+	GotoCalendar Action = "goto.calendar"
+	GotoSearch   Action = "goto.search"
+	GotoStats    Action = "goto.stats"
+	GotoSeason   Action = "goto.season"
+	GotoBacklog  Action = "goto.backlog"
+	OpenUser     Action = "open_user"
+	Palette      Action = "palette"
+	Help         Action = "help"
+	Quit         Action = "quit"
+	Back         Action = "back"
+	Forward      Action = "forward"
+	Recent       Action = "recent"
+
+	ScrollDown Action = "scroll.down"
+	ScrollUp   Action = "scroll.up"
+	FocusLeft  Action = "focus.left"
+	FocusRight Action = "focus.right"
+	FocusNext  Action = "focus.next"
+	Maximize   Action = "maximize"
+
+	Open         Action = "open"
+	Edit         Action = "edit"
+	Refresh      Action = "refresh"
+	NextPage     Action = "next_page"
+	PrevPage     Action = "prev_page"
+	SwitchStatus Action = "switch_status"
+	Filter       Action = "filter"
+	Sort         Action = "sort"
+	SubjectSplit Action = "subject_split"
+	AddWish      Action = "add_wish"
+	EpisodeNext  Action = "episode.next"
+	EpisodePrev  Action = "episode.prev"
+
+	CalendarMine Action = "calendar.mine"
+	CalendarHide Action = "calendar.hide"
+	Timetable    Action = "calendar.timetable"
+
+	BacklogMark      Action = "backlog.mark"
+	BacklogMarkStale Action = "backlog.mark_stale"
+	BacklogStash     Action = "backlog.stash"
+	BacklogDrop      Action = "backlog.drop"
+	BacklogCatchUp   Action = "backlog.catch_up"
+
+	EpisodeToggle  Action = "episode.toggle"
+	EpisodeDone    Action = "episode.done"
+	EpisodeWish    Action = "episode.wish"
+	EpisodeDropped Action = "episode.dropped"
+)
+
+// Descriptions are shown on the help page
+var Descriptions = map[Action]string{
+	GotoWatching:     "Go to watching list",
+	GotoWish:         "Go to wish list",
+	GotoDone:         "Go to done list",
+	GotoStashed:      "Go to stashed list",
+	GotoDropped:      "Go to dropped list",
+	GotoCalendar:     "Go to calendar",
+	GotoSearch:       "Go to search",
+	GotoStats:        "Go to stats",
+	GotoSeason:       "Go to season",
+	GotoBacklog:      "Go to backlog",
+	OpenUser:         "Open user collection",
+	Palette:          "Command palette",
+	Help:             "Show this help",
+	Quit:             "Quit",
+	Back:             "Back",
+	Forward:          "Forward",
+	Recent:           "Recently viewed subjects",
+	ScrollDown:       "Move down",
+	ScrollUp:         "Move up",
+	FocusLeft:        "Switch to left",
+	FocusRight:       "Switch to right",
+	FocusNext:        "Switch pane",
+	Maximize:         "Maximize pane",
+	Open:             "View subject",
+	Edit:             "Edit collection",
+	Refresh:          "Refresh",
+	NextPage:         "Load next page",
+	PrevPage:         "Load previous page",
+	SwitchStatus:     "Switch status (user list)",
+	Filter:           "Filter list",
+	Sort:             "Change sort order",
+	SubjectSplit:     "Show subject beside the list",
+	AddWish:          "Add to wish list",
+	EpisodeNext:      "Mark next episode",
+	EpisodePrev:      "Unmark last episode",
+	CalendarMine:     "Highlight my watching and wish lists",
+	CalendarHide:     "Hide subjects not in my lists",
+	Timetable:        "Show air times by hour",
+	BacklogMark:      "Mark subject for bulk actions",
+	BacklogMarkStale: "Mark stale subjects",
+	BacklogStash:     "Move marked subjects to on-hold",
+	BacklogDrop:      "Drop marked subjects",
+	BacklogCatchUp:   "Mark aired episodes of marked subjects watched",
+	EpisodeToggle:    "Toggle episode done",
+	EpisodeDone:      "Mark episode done",
+	EpisodeWish:      "Mark episode wish",
+	EpisodeDropped:   "Mark episode dropped",
+}
+
+// Scope is a group of actions handled by one view
+type Scope struct {
+	Name    string
+	Actions []Action
+}
+
+var (
+	Global = Scope{"General", []Action{
+		GotoWatching, GotoWish, GotoDone, GotoStashed, GotoDropped, GotoCalendar, GotoSearch, GotoStats,
+		GotoSeason, GotoBacklog, OpenUser, Palette, Help, Quit, Back, Forward, Recent,
+	}}
+	Scroll     = Scope{"Navigation", []Action{ScrollDown, ScrollUp}}
+	Collection = Scope{"Collection", []Action{
+		FocusLeft, FocusRight, Maximize, Edit, Refresh, NextPage, SwitchStatus, Filter, Sort, SubjectSplit, EpisodeNext, EpisodePrev,
+	}}
+	Subject  = Scope{"Subject", []Action{FocusLeft, FocusRight, FocusNext, Maximize, Edit, Refresh}}
+	Episodes = Scope{"Episode grid", []Action{EpisodeToggle, EpisodeDone, EpisodeWish, EpisodeDropped}}
+	Search   = Scope{"Search", []Action{Open, NextPage, PrevPage}}
+	Calendar = Scope{"Calendar", []Action{Open, CalendarMine, CalendarHide, Timetable}}
+	Stats    = Scope{"Stats", []Action{Refresh}}
+	Season   = Scope{"Season", []Action{Open, NextPage, PrevPage, Sort, AddWish, Refresh}}
+	Backlog  = Scope{"Backlog", []Action{
+		Open, BacklogMark, BacklogMarkStale, BacklogStash, BacklogDrop, BacklogCatchUp, Refresh,
+	}}
+
+	// Scopes in the order of the help page
+	Scopes = []Scope{Global, Scroll, Collection, Subject, Episodes, Search, Calendar, Stats, Season, Backlog}
+
+	// contexts are scopes active at the same time. Keys must be unique in each.
+	contexts = [][]Scope{
+		{Global, Scroll, Collection},
+		{Global, Scroll, Subject, Episodes},
+		{Global, Search},
+		{Global, Calendar},
+		{Global, Stats},
+		{Global, Season},
+		{Global, Backlog},
+	}
+)
+
+var vimKeys = map[Action][]string{
+	GotoWatching: {"1"},
+	GotoWish:     {"2"},
+	GotoDone:     {"3"},
+	GotoStashed:  {"4"},
+	GotoDropped:  {"5"},
+	GotoCalendar: {"6"},
+	GotoSearch:   {"7"},
+	GotoStats:    {"8"},
+	GotoSeason:   {"9"},
+	GotoBacklog:  {"B"},
+	OpenUser:     {"u"},
+	Palette:      {":", "ctrl-p"},
+	Help:         {"?"},
+	Quit:         {"Q"},
+	Back:         {"q", "esc"},
+	Forward:      {"]"},
+	Recent:       {"r"},
+
+	ScrollDown: {"j"},
+	ScrollUp:   {"k"},
+	FocusLeft:  {"h", "left"},
+	FocusRight: {"l", "right"},
+	FocusNext:  {"tab"},
+	Maximize:   {"z"},
+
+	Open:         {"space", "enter"},
+	Edit:         {"e"},
+	Refresh:      {"R"},
+	NextPage:     {"n"},
+	PrevPage:     {"p"},
+	SwitchStatus: {"s"},
+	Filter:       {"/"},
+	Sort:         {"o"},
+	SubjectSplit: {"v"},
+	AddWish:      {"w"},
+	EpisodeNext:  {"+"},
+	EpisodePrev:  {"-"},
+
+	CalendarMine: {"m"},
+	CalendarHide: {"f"},
+	Timetable:    {"t"},
+
+	BacklogMark:      {"m"},
+	BacklogMarkStale: {"a"},
+	BacklogStash:     {"s"},
+	BacklogDrop:      {"d"},
+	BacklogCatchUp:   {"c"},
+
+	EpisodeToggle:  {"space"},
+	EpisodeDone:    {"d"},
+	EpisodeWish:    {"w"},
+	EpisodeDropped: {"x"},
+}
+
+// Presets are complete keymaps selected in the config
+var Presets = map[string]map[Action][]string{
+	"vim":   vimKeys,
+	"emacs": emacsKeys(),
+}
+
+func emacsKeys() map[Action][]string {
+	keys := make(map[Action][]string, len(vimKeys))
+	for action, k := range vimKeys {
+		keys[action] = k
+	}
+	keys[Palette] = []string{"alt-x"}
+	keys[Quit] = []string{"ctrl-q"}
+	keys[Back] = []string{"ctrl-g", "esc"}
+	keys[ScrollDown] = []string{"ctrl-n"}
+	keys[ScrollUp] = []string{"ctrl-p"}
+	keys[FocusLeft] = []string{"ctrl-b", "left"}
+	keys[FocusRight] = []string{"ctrl-f", "right"}
+	keys[NextPage] = []string{"alt-n"}
+	keys[PrevPage] = []string{"alt-p"}
+	keys[Filter] = []string{"ctrl-s"}
+	return keys
+}
+
+// Keymap holds the keys of every action
+type Keymap struct {
+	keys map[Action][]string
+}
+
+// Default returns the vim preset
+func Default() *Keymap {
+	k, _ := New("vim", nil)
+	return k
+}
+
+// New creates a keymap from a preset. bindings replace keys of some actions.
+// Unknown presets, actions and keys are errors.
+func New(preset string, bindings map[string][]string) (*Keymap, error) {
+	if preset == "" {
+		preset = "vim"
+	}
+	base, ok := Presets[preset]
+	if !ok {
+		return nil, fmt.Errorf("unknown keymap preset %q", preset)
+	}
+	k := &Keymap{keys: make(map[Action][]string, len(base))}
+	for action, keys := range base {
+		k.keys[action] = keys
+	}
+	for name, keys := range bindings {
+		action := Action(name)
+		if _, ok := Descriptions[action]; !ok {
+			return nil, fmt.Errorf("unknown action %q", name)
+		}
+		for _, key := range keys {
+			if !validKey(key) {
+				return nil, fmt.Errorf("invalid key %q of action %s", key, name)
+			}
+		}
+		k.keys[action] = keys
+	}
+	return k, nil
+}
+
+// Keys returns keys bound to an action
+func (k *Keymap) Keys(action Action) []string {
+	return k.keys[action]
+}
+
+// Match returns the action of the scope bound to the key of event. Empty if none.
+func (k *Keymap) Match(scope Scope, event *tcell.EventKey) Action {
+	name := KeyName(event)
+	for _, action := range scope.Actions {
+		if slices.Contains(k.keys[action], name) {
+			return action
+		}
+	}
+	return ""
+}
+
+// Conflict is a key bound to several actions that are active at the same time
+type Conflict struct {
+	Key     string
+	Actions []Action
+}
+
+func (c Conflict) String() string {
+	names := make([]string, len(c.Actions))
+	for i, a := range c.Actions {
+		names[i] = string(a)
+	}
+	return fmt.Sprintf("%s is bound to %s", c.Key, strings.Join(names, ", "))
+}
+
+// Conflicts finds keys bound to more than one action of the same view.
+func (k *Keymap) Conflicts() []Conflict {
+	var conflicts []Conflict
+	seen := make(map[string]bool)
+	for _, scopes := range contexts {
+		actionsByKey := make(map[string][]Action)
+		for _, scope := range scopes {
+			for _, action := range scope.Actions {
+				for _, key := range k.keys[action] {
+					if !slices.Contains(actionsByKey[key], action) {
+						actionsByKey[key] = append(actionsByKey[key], action)
+					}
+				}
+			}
+		}
+		for key, actions := range actionsByKey {
+			if len(actions) < 2 {
+				continue
+			}
+			c := Conflict{Key: key, Actions: actions}
+			if !seen[c.String()] {
+				seen[c.String()] = true
+				conflicts = append(conflicts, c)
+			}
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Key < conflicts[j].Key
+	})
+	return conflicts
+}
+
+// specialKeys are names of non-rune keys such as "enter" and "ctrl-p"
+var specialKeys = func() map[string]tcell.Key {
+	keys := make(map[string]tcell.Key, len(tcell.KeyNames))
+	for key, name := range tcell.KeyNames {
+		keys[strings.ToLower(name)] = key
+	}
+	return keys
+}()
+
+func validKey(key string) bool {
+	if _, ok := specialKeys[key]; ok || key == "space" || key == "alt-space" {
+		return true
+	}
+	key = strings.TrimPrefix(key, "alt-")
+	return utf8.RuneCountInString(key) == 1
+}
+
+// KeyName returns the name of the key used in keymaps.
+// Runes are themselves, special keys are lower case tcell names like "ctrl-p".
+func KeyName(event *tcell.EventKey) string {
+	if event.Key() != tcell.KeyRune {
+		if name, ok := tcell.KeyNames[event.Key()]; ok {
+			return strings.ToLower(name)
+		}
+		return fmt.Sprintf("key-%d", event.Key())
+	}
+	name := string(event.Rune())
+	if event.Rune() == ' ' {
+		name = "space"
+	}
+	if event.Modifiers()&tcell.ModAlt != 0 {
+		return "alt-" + name
+	}
+	return name
+}
+
+// DisplayName formats a key for the help page, e.g. "Ctrl-P", "Space" or "j"
+func DisplayName(key string) string {
+	if k, ok := specialKeys[key]; ok {
+		return tcell.KeyNames[k]
+	}
+	if rest, ok := strings.CutPrefix(key, "alt-"); ok {
+		return "Alt-" + DisplayName(rest)
+	}
+	if key == "space" {
+		return "Space"
+	}
+	return key
+}
@@ -0,0 +1,110 @@
+// Package loader runs network fetches of the TUI in the background.
+package loader
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/iucario/bangumi-go/internal/ui"
+	"github.com/rivo/tview"
+)
+
+// Loader runs fetches off the UI goroutine and applies the results with QueueUpdateDraw.
+// Loads share a key per view. Starting a load cancels the previous one with the same key,
+// so results of stale requests are never applied.
+type Loader struct {
+	app     *tview.Application
+	spinner *ui.Spinner
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+	seq     map[string]uint64
+	// OnError is called on the UI goroutine when a fetch fails. Cancelled fetches are not reported.
+	OnError func(key string, err error)
+}
+
+// New creates a loader. spinner is optional and spins while any load is pending.
+func New(app *tview.Application, spinner *ui.Spinner) *Loader {
+	return &Loader{
+		app:     app,
+		spinner: spinner,
+		cancels: make(map[string]context.CancelFunc),
+		seq:     make(map[string]uint64),
+	}
+}
+
+// Load fetches in a new goroutine and calls apply with the result on the UI goroutine.
+// Errors are reported with OnError before apply is called, so apply only has to clean up.
+// fetch should pass ctx to its requests, e.g. with api.HTTPClient.WithContext.
+func Load[T any](l *Loader, key string, fetch func(ctx context.Context) (T, error), apply func(T, error)) {
+	ctx, seq := l.begin(key)
+	if l.spinner != nil {
+		l.spinner.Start()
+	}
+	go func() {
+		if l.spinner != nil {
+			defer l.spinner.Stop()
+		}
+		data, err := fetch(ctx)
+		l.app.QueueUpdateDraw(func() {
+			if !l.end(key, seq) {
+				slog.Debug("discarding stale load", "Key", key)
+				return
+			}
+			if err != nil {
+				slog.Error("loading", "Key", key, "Error", err)
+				if l.OnError != nil && !errors.Is(err, context.Canceled) {
+					l.OnError(key, err)
+				}
+			}
+			apply(data, err)
+		})
+	}()
+}
+
+// Cancel aborts the pending load of key if there is one.
+func (l *Loader) Cancel(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cancel, ok := l.cancels[key]; ok {
+		cancel()
+		delete(l.cancels, key)
+	}
+	l.seq[key]++
+}
+
+// Loading reports whether a load of key is pending.
+func (l *Loader) Loading(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.cancels[key]
+	return ok
+}
+
+// begin cancels the previous load of key and starts a new one.
+func (l *Loader) begin(key string) (context.Context, uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cancel, ok := l.cancels[key]; ok {
+		cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l.seq[key]++
+	l.cancels[key] = cancel
+	return ctx, l.seq[key]
+}
+
+// end finishes a load and reports whether it is still the latest one of key.
+func (l *Loader) end(key string, seq uint64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.seq[key] != seq {
+		return false
+	}
+	if cancel, ok := l.cancels[key]; ok {
+		cancel()
+		delete(l.cancels, key)
+	}
+	return true
+}
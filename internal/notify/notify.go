@@ -0,0 +1,136 @@
+// Package notify sends notifications to sinks like stdout, the desktop, webhooks or commands.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Kinds of notifications
+const (
+	NewEpisode = "episode" // An episode in the watching list aired
+	Behind     = "behind"  // Too many aired episodes are not watched
+)
+
+// Notification is sent to every sink. Webhooks and commands receive it as JSON.
+type Notification struct {
+	Kind      string    `json:"kind"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	URL       string    `json:"url"`
+	SubjectID int       `json:"subject_id"`
+	EpisodeID int       `json:"episode_id,omitempty"`
+	Episode   int       `json:"episode"`          // Episode number, or the latest aired episode if behind
+	Behind    int       `json:"behind,omitempty"` // Number of unwatched aired episodes
+	AiredAt   time.Time `json:"aired_at,omitzero"`
+}
+
+// Sink delivers notifications
+type Sink interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// Writer prints notifications as lines, e.g. to stdout
+type Writer struct {
+	W io.Writer
+}
+
+func (s Writer) Send(ctx context.Context, n Notification) error {
+	_, err := fmt.Fprintf(s.W, "%s %s: %s %s\n", time.Now().Format("2006-01-02 15:04"), n.Title, n.Body, n.URL)
+	return err
+}
+
+// Desktop shows notifications with notify-send, which talks to the notification daemon
+// over D-Bus, on Linux and BSD, and with osascript on macOS.
+type Desktop struct{}
+
+func (Desktop) Send(ctx context.Context, n Notification) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(n.Body), strconv.Quote(n.Title))
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	case "windows":
+		return errors.New("desktop notifications are not supported on windows")
+	default:
+		cmd = exec.CommandContext(ctx, "notify-send", "--app-name=bgm", n.Title, n.Body)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", cmd.Path, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// Webhook posts notifications as JSON
+type Webhook struct {
+	URL    string
+	Client *http.Client // http.DefaultClient if nil
+}
+
+func (s Webhook) Send(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: %s", s.URL, resp.Status)
+	}
+	return nil
+}
+
+// Command runs a shell command for each notification. The notification is passed as JSON
+// on stdin and in the environment variables BGM_KIND, BGM_TITLE, BGM_BODY, BGM_URL,
+// BGM_SUBJECT_ID and BGM_EPISODE.
+type Command struct {
+	Command string
+}
+
+func (s Command) Send(ctx context.Context, n Notification) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", s.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", s.Command)
+	}
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"BGM_KIND="+n.Kind,
+		"BGM_TITLE="+n.Title,
+		"BGM_BODY="+n.Body,
+		"BGM_URL="+n.URL,
+		"BGM_SUBJECT_ID="+strconv.Itoa(n.SubjectID),
+		"BGM_EPISODE="+strconv.Itoa(n.Episode),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("command %q: %w: %s", s.Command, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
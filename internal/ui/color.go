@@ -7,101 +7,106 @@ import (
 	"github.com/rivo/tview"
 )
 
-// "name": "synthwave-everything",
-var color = map[string]int32{
-	"black":               0xfefefe,
-	"red":                 0xf97e72,
-	"green":               0x72f1b8,
-	"yellow":              0xfede5d,
-	"blue":                0x6d77b3,
-	"purple":              0xc792ea,
-	"cyan":                0xf772e0,
-	"white":               0xfefefe,
-	"brightBlack":         0xfefefe,
-	"brightRed":           0xf88414,
-	"brightGreen":         0x72f1b8,
-	"brightYellow":        0xfff951,
-	"brightBlue":          0x36f9f6,
-	"brightPurple":        0xe1acff,
-	"brightCyan":          0xf92aad,
-	"brightWhite":         0xfefefe,
-	"background":          0x2a2139,
-	"foreground":          0xf0eff1,
-	"cursorColor":         0x72f1b8,
-	"selectionBackground": 0x181521,
-}
-
-var Styles = tview.Theme{
-	PrimitiveBackgroundColor:    tcell.NewHexColor(color["background"]),
-	ContrastBackgroundColor:     tcell.NewHexColor(color["blue"]),
-	MoreContrastBackgroundColor: tcell.NewHexColor(color["purple"]),
-	BorderColor:                 tcell.ColorGrey,
-	TitleColor:                  tcell.NewHexColor(color["purple"]),
-	GraphicsColor:               tcell.NewHexColor(color["red"]),
-	PrimaryTextColor:            tcell.NewHexColor(color["white"]),
-	SecondaryTextColor:          tcell.NewHexColor(color["yellow"]),
-	TertiaryTextColor:           tcell.NewHexColor(color["green"]),
-	InverseTextColor:            tcell.NewHexColor(color["brightBlue"]),
-	ContrastSecondaryTextColor:  tcell.NewHexColor(color["white"]),
+// Styles is the tview theme of the current theme. Set by ApplyTheme.
+var Styles tview.Theme
+
+// colorText wraps text in a color tag of a palette color
+func colorText(name string, text string) string {
+	return fmt.Sprintf("[%s]%s[-]", ColorTag(palette[name]), text)
 }
 
 func Yellow(text string) string {
-	return fmt.Sprintf("[#%06x]%s[-]", color["yellow"], text)
+	return colorText("yellow", text)
 }
 
 func Blue(text string) string {
-	return fmt.Sprintf("[#%06x]%s[-]", color["blue"], text)
+	return colorText("blue", text)
 }
 
 func Green(text string) string {
-	return fmt.Sprintf("[#%06x]%s[-]", color["green"], text)
+	return colorText("green", text)
 }
 
 func Cyan(text string) string {
-	return fmt.Sprintf("[#%06x]%s[-]", color["cyan"], text)
+	return colorText("cyan", text)
 }
 
 func Red(text string) string {
-	return fmt.Sprintf("[#%06x]%s[-]", color["red"], text)
+	return colorText("red", text)
 }
 
 func Black(text string) string {
-	return fmt.Sprintf("[#%06x]%s[-]", color["black"], text)
+	return colorText("background", text)
 }
 
 func Purple(text string) string {
-	return fmt.Sprintf("[#%06x]%s[-]", color["purple"], text)
+	return colorText("purple", text)
 }
 
 func White(text string) string {
-	return fmt.Sprintf("[#%06x]%s[-]", color["white"], text)
+	return colorText("foreground", text)
 }
 
 func Grey(text string) string {
-	return fmt.Sprintf("[%s]%s[-]", ColorToHex(tcell.ColorGrey), text)
-}
-
-func ColorToHex(color tcell.Color) string {
+	return colorText("grey", text)
+}
+
+// ColorTag formats a color for a color tag. The default color is "-". Named colors
+// keep their name so that terminals without true color get a palette color.
+func ColorTag(color tcell.Color) string {
+	if color == tcell.ColorDefault {
+		return "-"
+	}
+	if !color.IsRGB() {
+		if name := color.Name(); name != "" {
+			return name
+		}
+	}
 	r, g, b := color.RGB()
 	return fmt.Sprintf("#%02X%02X%02X", r, g, b)
 }
 
 func TertiaryText(text string) string {
-	return fmt.Sprintf("[%s]%s[-]", ColorToHex(Styles.TertiaryTextColor), text)
+	return fmt.Sprintf("[%s]%s[-]", ColorTag(Styles.TertiaryTextColor), text)
 }
 
 func SecondaryText(text string) string {
-	return fmt.Sprintf("[%s]%s[-]", ColorToHex(Styles.SecondaryTextColor), text)
+	return fmt.Sprintf("[%s]%s[-]", ColorTag(Styles.SecondaryTextColor), text)
 }
 
 func GraphicsColor(text string) string {
-	return fmt.Sprintf("[%s]%s[-]", ColorToHex(Styles.GraphicsColor), text)
+	return fmt.Sprintf("[%s]%s[-]", ColorTag(Styles.GraphicsColor), text)
 }
 
 func BorderColor(text string) string {
-	return fmt.Sprintf("[%s]%s[-]", ColorToHex(Styles.BorderColor), text)
+	return fmt.Sprintf("[%s]%s[-]", ColorTag(Styles.BorderColor), text)
 }
 
 func TitleColor(text string) string {
-	return fmt.Sprintf("[%s]%s[-]", ColorToHex(Styles.TitleColor), text)
+	return fmt.Sprintf("[%s]%s[-]", ColorTag(Styles.TitleColor), text)
+}
+
+// GreyColor is the color of unfocused borders and inactive items
+func GreyColor() tcell.Color {
+	return palette["grey"]
+}
+
+// RedColor is the color of errors
+func RedColor() tcell.Color {
+	return palette["red"]
+}
+
+// YellowColor is the color of headers
+func YellowColor() tcell.Color {
+	return palette["yellow"]
+}
+
+// GreenColor is the color of items in progress
+func GreenColor() tcell.Color {
+	return palette["green"]
+}
+
+// CyanColor is the color of planned items
+func CyanColor() tcell.Color {
+	return palette["cyan"]
 }
@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/iucario/bangumi-go/internal/cover"
+	"github.com/rivo/tview"
+)
+
+// blank fills the area of terminal graphics. It looks empty but differs from
+// the spaces of other primitives, so tcell repaints it when they draw over it.
+const blank = '⠀'
+
+// ImageView shows an image with half blocks, or reserves its area for terminal graphics.
+// Terminal graphics are written by AfterDraw after the screen is shown.
+type ImageView struct {
+	*tview.Box
+	image    image.Image
+	protocol cover.Protocol
+	text     string // shown when there is no image
+
+	area  image.Rectangle // cells of the image in the last draw
+	drawn bool            // drawn in the current frame
+	shown image.Rectangle // area where graphics are on the terminal, empty if none
+}
+
+func NewImageView(protocol cover.Protocol) *ImageView {
+	return &ImageView{Box: tview.NewBox(), protocol: protocol}
+}
+
+// SetImage replaces the image. nil shows the text instead.
+func (v *ImageView) SetImage(img image.Image) *ImageView {
+	v.image = img
+	v.shown = image.Rectangle{}
+	return v
+}
+
+// SetText sets the text shown without an image
+func (v *ImageView) SetText(text string) *ImageView {
+	v.text = text
+	return v
+}
+
+func (v *ImageView) Draw(screen tcell.Screen) {
+	v.DrawForSubclass(screen, v)
+	x, y, width, height := v.GetInnerRect()
+	v.drawn = true
+	v.area = image.Rectangle{}
+	if v.image == nil || v.protocol == cover.None {
+		tview.Print(screen, v.text, x, y+height/2, width, tview.AlignCenter, Styles.SecondaryTextColor)
+		return
+	}
+	cols, rows := cover.Fit(v.image, width, height)
+	if cols == 0 {
+		return
+	}
+	x += (width - cols) / 2
+	v.area = image.Rect(x, y, x+cols, y+rows)
+	if v.protocol == cover.HalfBlock {
+		for row, cells := range cover.HalfBlocks(v.image, cols, rows) {
+			for col, cell := range cells {
+				top, bottom := cell[0], cell[1]
+				style := tcell.StyleDefault.
+					Foreground(tcell.NewRGBColor(int32(top.R), int32(top.G), int32(top.B))).
+					Background(tcell.NewRGBColor(int32(bottom.R), int32(bottom.G), int32(bottom.B)))
+				screen.SetContent(x+col, y+row, '▀', nil, style)
+			}
+		}
+		return
+	}
+	style := tcell.StyleDefault.Background(Styles.PrimitiveBackgroundColor)
+	for row := range rows {
+		for col := range cols {
+			screen.SetContent(x+col, y+row, blank, nil, style)
+		}
+	}
+}
+
+// AfterDraw decides, before the screen is shown, what graphics to write to the terminal.
+// It returns the bytes to write after the screen is shown, or nil.
+// Graphics are written when the area is intact and removed when it is hidden or covered.
+func (v *ImageView) AfterDraw(screen tcell.Screen) []byte {
+	if v.protocol == cover.HalfBlock || v.protocol == cover.None {
+		return nil
+	}
+	intact := v.drawn && !v.area.Empty()
+	for y := v.area.Min.Y; intact && y < v.area.Max.Y; y++ {
+		for x := v.area.Min.X; x < v.area.Max.X; x++ {
+			if r, _, _, _ := screen.GetContent(x, y); r != blank {
+				intact = false
+				break
+			}
+		}
+	}
+	v.drawn = false
+
+	var buf bytes.Buffer
+	switch {
+	case intact && v.shown != v.area:
+		// Save the cursor so tcell keeps its own position
+		fmt.Fprintf(&buf, "\x1b7\x1b[%d;%dH", v.area.Min.Y+1, v.area.Min.X+1)
+		if v.protocol == cover.Kitty {
+			cover.ClearKitty(&buf)
+		}
+		if err := cover.Encode(&buf, v.image, v.area.Dx(), v.area.Dy(), v.protocol); err != nil {
+			return nil
+		}
+		buf.WriteString("\x1b8")
+		v.shown = v.area
+	case !intact && !v.shown.Empty():
+		// Other graphics are painted over by tcell
+		if v.protocol == cover.Kitty {
+			cover.ClearKitty(&buf)
+		}
+		v.shown = image.Rectangle{}
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	return buf.Bytes()
+}
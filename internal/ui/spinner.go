@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+// Spinner is an animated indicator shown while background work is pending.
+// Start and Stop are counted so overlapping work keeps it spinning.
+type Spinner struct {
+	*tview.TextView
+	app     *tview.Application
+	mu      sync.Mutex
+	pending int
+	stop    chan struct{}
+}
+
+// NewSpinner creates a spinner that redraws through app.
+func NewSpinner(app *tview.Application) *Spinner {
+	return &Spinner{
+		TextView: tview.NewTextView().SetTextAlign(tview.AlignRight).SetTextColor(Styles.TertiaryTextColor),
+		app:      app,
+	}
+}
+
+// Start marks a piece of work as pending. It is safe to call from any goroutine.
+func (s *Spinner) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending++
+	if s.pending == 1 {
+		s.stop = make(chan struct{})
+		go s.spin(s.stop)
+	}
+}
+
+// Stop marks a piece of work as done. The spinner is cleared when nothing is pending.
+func (s *Spinner) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == 0 {
+		return
+	}
+	s.pending--
+	if s.pending == 0 {
+		close(s.stop)
+	}
+}
+
+func (s *Spinner) spin(stop chan struct{}) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for i := 0; ; i++ {
+		select {
+		case <-stop:
+			s.app.QueueUpdateDraw(func() {
+				s.SetText("")
+			})
+			return
+		case <-ticker.C:
+			frame := string(spinnerFrames[i%len(spinnerFrames)])
+			s.app.QueueUpdateDraw(func() {
+				s.SetText(frame)
+			})
+		}
+	}
+}
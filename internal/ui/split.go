@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// minPaneWidth keeps both panes usable while dragging
+const minPaneWidth = 10
+
+// SplitPane shows two panes side by side. The border between them can be dragged
+// with the mouse, and one pane can be maximized.
+type SplitPane struct {
+	*tview.Box
+	left, right tview.Primitive
+	ratio       float64 // Width of the left pane in the total width
+	maximized   tview.Primitive
+	dragging    bool
+	changed     func(ratio float64)
+}
+
+// NewSplitPane creates a split. ratio is the share of the left pane, between 0 and 1.
+func NewSplitPane(left, right tview.Primitive, ratio float64) *SplitPane {
+	return &SplitPane{
+		Box:   tview.NewBox(),
+		left:  left,
+		right: right,
+		ratio: ratio,
+	}
+}
+
+// SetChangedFunc is called with the new ratio when a drag ends
+func (s *SplitPane) SetChangedFunc(handler func(ratio float64)) *SplitPane {
+	s.changed = handler
+	return s
+}
+
+// SetRight replaces the right pane
+func (s *SplitPane) SetRight(right tview.Primitive) *SplitPane {
+	if s.maximized == s.right {
+		s.maximized = right
+	}
+	s.right = right
+	return s
+}
+
+// Right returns the right pane
+func (s *SplitPane) Right() tview.Primitive {
+	return s.right
+}
+
+// Ratio returns the share of the left pane
+func (s *SplitPane) Ratio() float64 {
+	return s.ratio
+}
+
+// ToggleMaximize shows only the focused pane, or both panes again
+func (s *SplitPane) ToggleMaximize() {
+	if s.maximized != nil {
+		s.maximized = nil
+		return
+	}
+	s.maximized = s.left
+	if s.right.HasFocus() {
+		s.maximized = s.right
+	}
+}
+
+// IsMaximized reports whether only one pane is shown
+func (s *SplitPane) IsMaximized() bool {
+	return s.maximized != nil
+}
+
+// splitX returns the first column of the right pane
+func (s *SplitPane) splitX() int {
+	x, _, width, _ := s.GetInnerRect()
+	left := int(float64(width) * s.ratio)
+	left = max(min(left, width-minPaneWidth), min(minPaneWidth, width))
+	return x + left
+}
+
+func (s *SplitPane) Draw(screen tcell.Screen) {
+	s.DrawForSubclass(screen, s)
+	x, y, width, height := s.GetInnerRect()
+	if s.maximized != nil {
+		// Follow the focus so the focused pane is never hidden
+		if s.maximized == s.left && s.right.HasFocus() {
+			s.maximized = s.right
+		} else if s.maximized == s.right && s.left.HasFocus() {
+			s.maximized = s.left
+		}
+		s.maximized.SetRect(x, y, width, height)
+		s.maximized.Draw(screen)
+		return
+	}
+	split := s.splitX()
+	s.left.SetRect(x, y, split-x, height)
+	s.right.SetRect(split, y, x+width-split, height)
+	s.left.Draw(screen)
+	s.right.Draw(screen)
+}
+
+func (s *SplitPane) Focus(delegate func(p tview.Primitive)) {
+	if s.right.HasFocus() {
+		delegate(s.right)
+		return
+	}
+	delegate(s.left)
+}
+
+func (s *SplitPane) HasFocus() bool {
+	return s.left.HasFocus() || s.right.HasFocus()
+}
+
+// InputHandler passes keys to the focused pane
+func (s *SplitPane) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return s.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		for _, pane := range []tview.Primitive{s.left, s.right} {
+			if pane.HasFocus() {
+				if handler := pane.InputHandler(); handler != nil {
+					handler(event, setFocus)
+				}
+				return
+			}
+		}
+	})
+}
+
+// PasteHandler passes pasted text to the focused pane
+func (s *SplitPane) PasteHandler() func(text string, setFocus func(p tview.Primitive)) {
+	return s.WrapPasteHandler(func(text string, setFocus func(p tview.Primitive)) {
+		for _, pane := range []tview.Primitive{s.left, s.right} {
+			if pane.HasFocus() {
+				if handler := pane.PasteHandler(); handler != nil {
+					handler(text, setFocus)
+				}
+				return
+			}
+		}
+	})
+}
+
+// MouseHandler drags the border between the panes and passes other events to the panes.
+// The border is the right border of the left pane and the left border of the right pane.
+func (s *SplitPane) MouseHandler() func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (bool, tview.Primitive) {
+	return s.WrapMouseHandler(func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (bool, tview.Primitive) {
+		mx, my := event.Position()
+		if s.dragging {
+			switch action {
+			case tview.MouseMove:
+				x, _, width, _ := s.GetInnerRect()
+				if width > 0 {
+					s.ratio = min(max(float64(mx-x)/float64(width), 0), 1)
+				}
+				return true, s
+			case tview.MouseLeftUp:
+				s.dragging = false
+				// Store the ratio actually drawn
+				x, _, width, _ := s.GetInnerRect()
+				if width > 0 {
+					s.ratio = float64(s.splitX()-x) / float64(width)
+				}
+				if s.changed != nil {
+					s.changed(s.ratio)
+				}
+				return true, nil
+			}
+			return true, s
+		}
+		if !s.InRect(mx, my) {
+			return false, nil
+		}
+		if split := s.splitX(); s.maximized == nil && action == tview.MouseLeftDown && (mx == split-1 || mx == split) {
+			s.dragging = true
+			return true, s
+		}
+		for _, pane := range []tview.Primitive{s.left, s.right} {
+			if s.maximized != nil && pane != s.maximized {
+				continue
+			}
+			if consumed, capture := pane.MouseHandler()(action, event, setFocus); consumed {
+				return consumed, capture
+			}
+		}
+		return false, nil
+	})
+}
@@ -0,0 +1,285 @@
+package ui
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"gopkg.in/yaml.v3"
+)
+
+// Theme is a color scheme. Colors are names like "red", hex like "#2a2139" or "default".
+// A custom theme starts from Base and replaces the colors it sets.
+type Theme struct {
+	Base         string `json:"base,omitempty" toml:"base" yaml:"base"`
+	Background   string `json:"background,omitempty" toml:"background" yaml:"background"`
+	Foreground   string `json:"foreground,omitempty" toml:"foreground" yaml:"foreground"`
+	Border       string `json:"border,omitempty" toml:"border" yaml:"border"`
+	Title        string `json:"title,omitempty" toml:"title" yaml:"title"`
+	Graphics     string `json:"graphics,omitempty" toml:"graphics" yaml:"graphics"`
+	Secondary    string `json:"secondary,omitempty" toml:"secondary" yaml:"secondary"`
+	Tertiary     string `json:"tertiary,omitempty" toml:"tertiary" yaml:"tertiary"`
+	Inverse      string `json:"inverse,omitempty" toml:"inverse" yaml:"inverse"`
+	Contrast     string `json:"contrast,omitempty" toml:"contrast" yaml:"contrast"`
+	MoreContrast string `json:"more_contrast,omitempty" toml:"more_contrast" yaml:"more_contrast"`
+	Red          string `json:"red,omitempty" toml:"red" yaml:"red"`
+	Green        string `json:"green,omitempty" toml:"green" yaml:"green"`
+	Yellow       string `json:"yellow,omitempty" toml:"yellow" yaml:"yellow"`
+	Blue         string `json:"blue,omitempty" toml:"blue" yaml:"blue"`
+	Cyan         string `json:"cyan,omitempty" toml:"cyan" yaml:"cyan"`
+	Purple       string `json:"purple,omitempty" toml:"purple" yaml:"purple"`
+	Grey         string `json:"grey,omitempty" toml:"grey" yaml:"grey"`
+}
+
+const DefaultTheme = "dark"
+
+//go:embed themes/*.toml
+var builtinThemes embed.FS
+
+// Themes are the built-in themes in themes/*.toml. "ansi" only uses the 16 terminal colors
+// and "mono" keeps the colors of the terminal for NO_COLOR.
+var Themes = loadBuiltinThemes()
+
+func loadBuiltinThemes() map[string]Theme {
+	files, err := fs.Glob(builtinThemes, "themes/*.toml")
+	if err != nil {
+		panic(err)
+	}
+	themes := make(map[string]Theme, len(files))
+	for _, file := range files {
+		data, err := builtinThemes.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+		name, t, err := DecodeTheme(path.Base(file), data)
+		if err != nil {
+			panic(err)
+		}
+		themes[name] = t
+	}
+	return themes
+}
+
+// ThemeExtensions are the formats of theme files
+var ThemeExtensions = []string{".toml", ".yaml", ".yml", ".json"}
+
+// DecodeTheme reads a theme file. The format is chosen by the extension of filename
+// and the name of the theme is filename without it. Unknown keys are errors.
+func DecodeTheme(filename string, data []byte) (string, Theme, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	var t Theme
+	switch ext {
+	case ".toml":
+		meta, err := toml.Decode(string(data), &t)
+		if err != nil {
+			return name, t, fmt.Errorf("theme %s: %w", filename, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return name, t, fmt.Errorf("theme %s: unknown key %s", filename, undecoded[0])
+		}
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&t); err != nil && !errors.Is(err, io.EOF) {
+			return name, t, fmt.Errorf("theme %s: %w", filename, err)
+		}
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&t); err != nil {
+			return name, t, fmt.Errorf("theme %s: %w", filename, err)
+		}
+	default:
+		return name, t, fmt.Errorf("theme %s: unknown format, must be one of %s", filename, strings.Join(ThemeExtensions, ", "))
+	}
+	return name, t, nil
+}
+
+// palette holds the colors of the current theme for text helpers
+var palette = map[string]tcell.Color{}
+
+// CurrentTheme is the name of the applied theme
+var CurrentTheme string
+
+func init() {
+	if err := ApplyTheme(DefaultTheme, nil); err != nil {
+		panic(err)
+	}
+}
+
+// ThemeNames lists built-in and custom themes, sorted
+func ThemeNames(custom map[string]Theme) []string {
+	var names []string
+	for name := range Themes {
+		names = append(names, name)
+	}
+	for name := range custom {
+		if _, ok := Themes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ResolveTheme finds a theme by name. Custom themes override built-in themes of the same name.
+func ResolveTheme(name string, custom map[string]Theme) (Theme, error) {
+	return resolveTheme(name, custom, 0)
+}
+
+func resolveTheme(name string, custom map[string]Theme, depth int) (Theme, error) {
+	if depth > len(custom) {
+		return Theme{}, fmt.Errorf("theme %s inherits from itself", name)
+	}
+	t, ok := custom[name]
+	if !ok {
+		builtin, ok := Themes[name]
+		if !ok {
+			return Theme{}, fmt.Errorf("unknown theme %q", name)
+		}
+		return builtin, nil
+	}
+	baseName := t.Base
+	if baseName == "" {
+		baseName = DefaultTheme
+	}
+	// A custom theme named like a built-in theme extends the built-in one
+	if baseName == name {
+		base, ok := Themes[name]
+		if !ok {
+			return Theme{}, fmt.Errorf("theme %s inherits from itself", name)
+		}
+		return mergeTheme(base, t), nil
+	}
+	base, err := resolveTheme(baseName, custom, depth+1)
+	if err != nil {
+		return Theme{}, err
+	}
+	return mergeTheme(base, t), nil
+}
+
+// mergeTheme replaces colors of base with the colors set in t
+func mergeTheme(base, t Theme) Theme {
+	merged := base
+	src := reflect.ValueOf(t)
+	dst := reflect.ValueOf(&merged).Elem()
+	for i := range src.NumField() {
+		if v := src.Field(i).String(); v != "" {
+			dst.Field(i).SetString(v)
+		}
+	}
+	merged.Base = ""
+	return merged
+}
+
+func parseColor(s string) (tcell.Color, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "default" {
+		return tcell.ColorDefault, nil
+	}
+	c := tcell.GetColor(s)
+	if c == tcell.ColorDefault {
+		return c, fmt.Errorf("invalid color %q", s)
+	}
+	return c, nil
+}
+
+// ApplyTheme sets Styles and the text colors to a theme.
+// Primitives created before keep their colors.
+func ApplyTheme(name string, custom map[string]Theme) error {
+	t, err := ResolveTheme(name, custom)
+	if err != nil {
+		return err
+	}
+	colors := map[string]string{
+		"background": t.Background, "foreground": t.Foreground, "border": t.Border, "title": t.Title,
+		"graphics": t.Graphics, "secondary": t.Secondary, "tertiary": t.Tertiary, "inverse": t.Inverse,
+		"contrast": t.Contrast, "more_contrast": t.MoreContrast, "red": t.Red, "green": t.Green,
+		"yellow": t.Yellow, "blue": t.Blue, "cyan": t.Cyan, "purple": t.Purple, "grey": t.Grey,
+	}
+	parsed := make(map[string]tcell.Color, len(colors))
+	for key, value := range colors {
+		c, err := parseColor(value)
+		if err != nil {
+			return fmt.Errorf("theme %s: %s: %w", name, key, err)
+		}
+		parsed[key] = c
+	}
+	palette = parsed
+	Styles = tview.Theme{
+		PrimitiveBackgroundColor:    parsed["background"],
+		ContrastBackgroundColor:     parsed["contrast"],
+		MoreContrastBackgroundColor: parsed["more_contrast"],
+		BorderColor:                 parsed["border"],
+		TitleColor:                  parsed["title"],
+		GraphicsColor:               parsed["graphics"],
+		PrimaryTextColor:            parsed["foreground"],
+		SecondaryTextColor:          parsed["secondary"],
+		TertiaryTextColor:           parsed["tertiary"],
+		InverseTextColor:            parsed["inverse"],
+		ContrastSecondaryTextColor:  parsed["foreground"],
+	}
+	tview.Styles = Styles
+	CurrentTheme = name
+	return nil
+}
+
+// SelectedStyle is the style of selected items. A theme that keeps the colors of the
+// terminal selects with reverse video, as swapping default colors shows nothing.
+// The zero style keeps the default of tview.
+func SelectedStyle() tcell.Style {
+	if Styles.PrimitiveBackgroundColor == tcell.ColorDefault || Styles.PrimaryTextColor == tcell.ColorDefault {
+		return tcell.StyleDefault.Reverse(true)
+	}
+	return tcell.StyleDefault
+}
+
+// NewList creates a list whose selection is visible in every theme
+func NewList() *tview.List {
+	list := tview.NewList()
+	if style := SelectedStyle(); style != tcell.StyleDefault {
+		list.SetSelectedStyle(style)
+	}
+	return list
+}
+
+// NewTable creates a table whose selection is visible in every theme
+func NewTable() *tview.Table {
+	return tview.NewTable().SetSelectedStyle(SelectedStyle())
+}
+
+// FallbackTheme picks a theme for limited terminals. "mono" if NO_COLOR is set,
+// "ansi" if the terminal does not have 256 colors, else empty.
+func FallbackTheme() string {
+	if os.Getenv("NO_COLOR") != "" {
+		return "mono"
+	}
+	// Modern Windows consoles support true color without setting TERM
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+	switch os.Getenv("COLORTERM") {
+	case "truecolor", "24bit":
+		return ""
+	}
+	term := os.Getenv("TERM")
+	if strings.Contains(term, "256") || strings.Contains(term, "truecolor") || strings.Contains(term, "direct") {
+		return ""
+	}
+	return "ansi"
+}
@@ -4,14 +4,23 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	_ "time/tzdata" // Timezones of the config on systems without a tz database
 
 	"github.com/iucario/bangumi-go/cmd"
 	_ "github.com/iucario/bangumi-go/cmd/auth"
+	_ "github.com/iucario/bangumi-go/cmd/backlog"
 	_ "github.com/iucario/bangumi-go/cmd/calendar"
+	_ "github.com/iucario/bangumi-go/cmd/compare"
+	_ "github.com/iucario/bangumi-go/cmd/episode"
 	_ "github.com/iucario/bangumi-go/cmd/list"
+	_ "github.com/iucario/bangumi-go/cmd/recommend"
 	_ "github.com/iucario/bangumi-go/cmd/search"
+	_ "github.com/iucario/bangumi-go/cmd/season"
+	_ "github.com/iucario/bangumi-go/cmd/stats"
 	_ "github.com/iucario/bangumi-go/cmd/subject"
 	_ "github.com/iucario/bangumi-go/cmd/ui"
+	_ "github.com/iucario/bangumi-go/cmd/user"
+	_ "github.com/iucario/bangumi-go/cmd/watch"
 )
 
 func main() {
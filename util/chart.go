@@ -0,0 +1,16 @@
+package util
+
+import "strings"
+
+var partialBlocks = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// Bar returns a horizontal text bar of at most width cells for value out of maxValue.
+// Eighth blocks are used so small differences are still visible.
+func Bar(value, maxValue float64, width int) string {
+	if maxValue <= 0 || value <= 0 || width <= 0 {
+		return ""
+	}
+	eighths := int(value / maxValue * float64(width*8))
+	eighths = max(1, min(eighths, width*8))
+	return strings.Repeat("█", eighths/8) + partialBlocks[eighths%8]
+}
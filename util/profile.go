@@ -0,0 +1,114 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultProfile keeps its files in BaseConfigDir, as before profiles existed
+const DefaultProfile = "default"
+
+var profilePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// activeProfile is set by the --profile flag. Empty means BGM_PROFILE or the switched profile.
+var activeProfile string
+
+// ValidateProfile checks that a profile name is usable as a directory name
+func ValidateProfile(name string) error {
+	if !profilePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile %q, use letters, digits, - and _", name)
+	}
+	return nil
+}
+
+// SetProfile selects the profile of this run. Empty selects the default choice.
+func SetProfile(name string) error {
+	if name != "" {
+		if err := ValidateProfile(name); err != nil {
+			return err
+		}
+	}
+	activeProfile = name
+	return nil
+}
+
+// Profile returns the active profile: the --profile flag, else BGM_PROFILE,
+// else the profile chosen with SwitchProfile, else the default profile.
+func Profile() string {
+	if activeProfile != "" {
+		return activeProfile
+	}
+	if name := os.Getenv("BGM_PROFILE"); name != "" && ValidateProfile(name) == nil {
+		return name
+	}
+	return CurrentProfile()
+}
+
+// currentProfilePath is the file with the name of the switched profile
+func currentProfilePath() string {
+	return filepath.Join(BaseConfigDir(), "profile")
+}
+
+// CurrentProfile returns the profile chosen with SwitchProfile
+func CurrentProfile() string {
+	b, err := os.ReadFile(currentProfilePath())
+	if err != nil {
+		return DefaultProfile
+	}
+	name := strings.TrimSpace(string(b))
+	if ValidateProfile(name) != nil {
+		return DefaultProfile
+	}
+	return name
+}
+
+// SwitchProfile makes a profile the one used without --profile and creates its directory
+func SwitchProfile(name string) error {
+	if err := ValidateProfile(name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(ProfileDir(name), 0o755); err != nil {
+		return err
+	}
+	if name == DefaultProfile {
+		err := os.Remove(currentProfilePath())
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return os.WriteFile(currentProfilePath(), []byte(name+"\n"), 0o644)
+}
+
+// ProfileDir returns the directory of a profile. Named profiles are under profiles/.
+func ProfileDir(name string) string {
+	if name == DefaultProfile {
+		return BaseConfigDir()
+	}
+	return filepath.Join(BaseConfigDir(), "profiles", name)
+}
+
+// ListProfiles returns the default profile and the profiles with a directory, sorted by name
+func ListProfiles() ([]string, error) {
+	profiles := []string{DefaultProfile}
+	entries, err := os.ReadDir(filepath.Join(BaseConfigDir(), "profiles"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return profiles, nil
+	}
+	if err != nil {
+		return profiles, err
+	}
+	for _, e := range entries {
+		if e.IsDir() && ValidateProfile(e.Name()) == nil && e.Name() != DefaultProfile {
+			profiles = append(profiles, e.Name())
+		}
+	}
+	slices.Sort(profiles[1:])
+	return profiles, nil
+}
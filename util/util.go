@@ -20,8 +20,8 @@ func Uint32ToString(i uint32) string {
 	return strconv.FormatUint(uint64(i), 10)
 }
 
-// Returns {HOME}/.config/bangumi-go
-func ConfigDir() string {
+// BaseConfigDir returns {HOME}/.config/bangumi-go, the directory of the default profile
+func BaseConfigDir() string {
 	usr, err := user.Current()
 	if err != nil {
 		slog.Error(err.Error())
@@ -29,3 +29,8 @@ func ConfigDir() string {
 	configDir := usr.HomeDir + "/.config/bangumi-go"
 	return configDir
 }
+
+// ConfigDir returns the directory of the active profile with its credential, config and state
+func ConfigDir() string {
+	return ProfileDir(Profile())
+}